@@ -1,8 +1,11 @@
 package auction
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"gitlab/nefco/auction/clock"
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/core/object"
 	"gitlab/nefco/auction/core/object/json_fileds"
@@ -12,6 +15,8 @@ import (
 	"gitlab/nefco/auction/process"
 	"gitlab/nefco/auction/process/rule"
 	"gitlab/nefco/auction/service"
+	"net/http"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -23,6 +28,7 @@ var (
 	userAlreadyExist        = errors.BadRequest("User already exist")
 	lotAlreadyExist         = errors.BadRequest("Lot already exist")
 	lotTypeAccessDenied     = errors.Forbidden("Lot type access denied")
+	previewDateInvalid      = errors.BadRequest("Preview date invalid")
 	processNotFound         = errors.BadRequest("Process not found")
 	lotNotBooked            = errors.BadRequest("Lot not booked")
 	lotNotConfirmed         = errors.BadRequest("Lot not confirmed")
@@ -36,6 +42,10 @@ var (
 	betOtherUser            = errors.BadRequest("Bid made by another user")
 	userBlocked             = errors.BadRequest("User bloked")
 	actNotFound 			= errors.NotFound("act not found")
+	lotEventsNotFound       = errors.NotFound("Lot events not found")
+	clockNotVirtual         = errors.BadRequest("Clock not virtual")
+	clockDurationInvalid    = errors.BadRequest("Clock duration invalid")
+	lotCreateFailed         = errors.NewError("Lot create failed", http.StatusInternalServerError)
 )
 
 type Action interface {
@@ -103,6 +113,23 @@ type CreateLot interface {
 	GetLot() *core.Lot
 }
 
+type CreateLots interface {
+	Action
+	GetLots() []*core.Lot
+	LotCreated(i int, lot *core.Lot)
+	LotExists(i int)
+	LotFailed(i int, err error)
+}
+
+type PreviewRules interface {
+	Action
+	GetRules() core.Rules
+	Group() string
+	Zone() string
+	Day() string
+	SetPreview(preview *core.RulePreview)
+}
+
 type GetLot interface {
 	ActionLot
 	SetLot(lot *core.Lot)
@@ -124,6 +151,35 @@ type History interface {
 	SetHistory(histories []*core.History)
 }
 
+type LotEvents interface {
+	ActionLot
+	SetLotEvents(events []*core.LotEvent)
+}
+
+type Processes interface {
+	Action
+	SetProcesses(info *core.ProcessesInfo)
+}
+
+type ProcessState interface {
+	ActionLot
+	SetProcessState(state *core.ProcessState)
+}
+
+type AdvanceClock interface {
+	Action
+	GetDuration() time.Duration
+	NextTimer() bool
+	SetNow(now time.Time)
+}
+
+type ReplayLot interface {
+	ActionLot
+	Version() uint
+	Save() bool
+	SetLot(lot *core.Lot)
+}
+
 type SendFeedback interface {
 	Message() string
 }
@@ -159,51 +215,90 @@ type Auction interface {
 	UnblockUser(userID uint) error
 	Lots(act Lots) error
 	CreateLot(act CreateLot) error
+	CreateLots(act CreateLots) error
+	PreviewRules(act PreviewRules) error
 	GetLot(act GetLot) error
 	UpdateLot(act UpdateLot) error
 	DeleteLot(act ActionLot) error
 	History(act History) error
+	LotEvents(act LotEvents) error
+	ReplayLot(act ReplayLot) error
+	Processes(act Processes) error
+	ProcessState(act ProcessState) error
+	AdvanceClock(act AdvanceClock) error
 	PlaceBet(act process.PlaceBet) error
 	CancelBet(act process.Action) error
+	PlaceProxy(act process.PlaceProxy) error
+	CancelProxy(act process.Action) error
 	ConfirmLot(act process.ConfirmLot) error
 	DeleteConfirmation(act ActionLot) error
 	CompleteLot(act CompleteLot) error
 	AcceptBet(act process.AcceptBet) error
+	PlaceReservation(act process.Action) error
+	CancelReservation(act process.Action) error
+	Templates(act Templates) error
+	CreateTemplate(act CreateTemplate) error
+	GetTemplate(act GetTemplate) error
+	UpdateTemplate(act UpdateTemplate) error
+	DeleteTemplate(act ActionTemplate) error
 	SendFeedback(act SendFeedback) error
 	GetBookingWindow(act GetBookingWindow) error
 	UpdateLotAct(act interfaces.EditActCommander) error
 	AutoBookingLot(act interfaces.AutoBookingCommander) error
 	AllowChangeAct(act interfaces.AllowChangeActCommander) error
+	Shutdown(ctx context.Context) error
 }
 
 type auction struct {
 	config         *Config
 	db             *db.DB
+	clock          clock.Clock
+	store          process.Store
 	notify         NotifyWriter
 	feedbackSvc    FeedbackService
 	cfgBackService *service.ConfigBackService
-	pp             map[uint]process.Process
+	pp             *process.Registry
+	jobsMu         sync.Mutex
+	jobs           map[lotJob]*job
+	runner         *core.User
+	done           chan struct{}
+	shutdownOnce   sync.Once
 	logger         *zap.Logger
 }
 
 func New(config *Config, db *db.DB, notify NotifyWriter,
 	feedbackSvc FeedbackService,
-	cfgBackService *service.ConfigBackService) *auction {
-	return &auction{
+	cfgBackService *service.ConfigBackService, clk clock.Clock) *auction {
+	auc := &auction{
 		config:         config,
 		db:             db,
+		clock:          clk,
 		notify:         notify,
 		feedbackSvc:    feedbackSvc,
 		cfgBackService: cfgBackService,
-		pp:             make(map[uint]process.Process),
+		pp:             process.NewRegistry(),
+		jobs:           make(map[lotJob]*job),
+		done:           make(chan struct{}),
 		logger:         zap.L().Named("auction"),
 	}
+	var fence process.Fence
+	if auc.leasing() {
+		fence = auc.fence
+	}
+	auc.store = process.NewStore(db, cfgBackService, fence)
+	return auc
+}
+
+func (auc *auction) now() time.Time {
+	return auc.clock.Now()
 }
 
 func (auc *auction) Restore(executor *core.User) error {
 	logger := auc.logger.Named("restore")
 
-	return auc.tx(func(tx *db.Tx) error {
+	auc.runner = executor
+
+	err := auc.tx(func(tx *db.Tx) error {
 		svc := service.NewLotService(tx)
 
 		lots, err := svc.Lots(nil)
@@ -212,52 +307,99 @@ func (auc *auction) Restore(executor *core.User) error {
 			return err
 		}
 
-		for _, lot := range lots {
-			rules, err := rule.Rules(lot.Rules, auc.config.RuleConfig)
-			if err != nil {
-				logger.Error("default rules failed", zap.Error(err))
-				return err
-			}
+		states, err := service.NewProcessStateService(tx).ProcessStates()
+		if err != nil {
+			logger.Error("get process states failed", zap.Error(err))
+			return err
+		}
 
-			var startRule process.Rule
+		lotStates := make(map[uint]*core.ProcessState, len(states))
+		for _, state := range states {
+			lotStates[state.LotID] = state
+		}
 
-			n := process.Now()
+		for _, lot := range lots {
+			auc.schedule(lot)
 
-			if lot.BookedAt != nil {
-				d := n.Sub(*lot.BookedAt)
-				if d < rule.DefaultConfirmDuration {
-					startRule, err = rule.NewConfirm(rule.DefaultConfirmDuration - d)
-					if err != nil {
-						logger.Error("confirm rule failed", zap.Error(err))
-						return err
-					}
-				}
+			if !lot.Published(auc.now()) {
+				continue
 			}
 
-			process, err := process.New(executor, lot, rules, auc.db,
-				auc, auc.cfgBackService, tx, startRule)
+			err := auc.restoreLot(tx, executor, lot, lotStates[lot.ID])
+			if _, ok := err.(*RemoteLot); ok {
+				continue
+			}
 			if err != nil {
-				logger.Error("process failed", zap.Error(err))
 				return err
 			}
-
-			auc.pp[lot.ID] = process
 		}
 
 		logger.Info("restore lots", zap.Int("count", len(lots)))
 
-		return nil
+		return auc.restoreTemplates(tx)
 	})
+	if err != nil {
+		return err
+	}
+
+	if auc.leasing() {
+		go auc.leases()
+		go auc.changes()
+	}
+
+	return nil
+}
+
+// restoreLot starts the process of the published lot from its saved state.
+// The process is started only if the lot is leased by the instance.
+func (auc *auction) restoreLot(tx *db.Tx, executor *core.User,
+	lot *core.Lot, state *core.ProcessState) error {
+	logger := auc.logger.Named("restore_lot").With(zap.Uint("lot_id", lot.ID))
+
+	rules, err := rule.Rules(lot.Rules, lot.Location(), auc.config.RuleConfig, auc.clock)
+	if err != nil {
+		logger.Error("default rules failed", zap.Error(err))
+		return err
+	}
+
+	var startRule process.Rule
+	var added time.Duration
+
+	if state != nil {
+		startRule, err = rule.Restore(state, rules, auc.clock)
+		if err != nil {
+			logger.Error("restore rule failed", zap.Error(err))
+			return err
+		}
+		if startRule != nil {
+			added = state.Added
+		}
+	}
+
+	n := auc.now()
+
+	if startRule == nil && lot.BookedAt != nil {
+		d := n.Sub(*lot.BookedAt)
+		if d < rule.DefaultConfirmDuration {
+			startRule, err = rule.NewConfirm(auc.clock, rule.DefaultConfirmDuration - d)
+			if err != nil {
+				logger.Error("confirm rule failed", zap.Error(err))
+				return err
+			}
+		}
+	}
+
+	return auc.startProcess(tx, executor, lot, rules, startRule, added)
 }
 
 func (auc *auction) Stop(process process.Process) error {
-	if _, ok := auc.pp[process.LotID()]; !ok {
+	if _, ok := auc.pp.Get(process.LotID()); !ok {
 		return processNotFound
 	}
 	if err := process.Stop(); err != nil {
 		return err
 	}
-	delete(auc.pp, process.LotID())
+	auc.pp.Remove(process)
 	return nil
 }
 
@@ -273,24 +415,49 @@ func (auc *auction) Users(act Users) error {
 			return err
 		}
 
+		scores, err := service.NewReliabilityService(tx).Scores()
+		if err != nil {
+			logger.Error("get scores failed", zap.Error(err))
+			return err
+		}
+
+		for _, user := range users {
+			if user.Level() != core.LevelUser {
+				continue
+			}
+			score, ok := scores[user.ID]
+			if !ok {
+				score = service.MaxScore
+			}
+			user.Score = &score
+		}
+
 		act.SetUsers(users)
 
 		return nil
 	})
 }
 
+func (auc *auction) LotAdded(lot *core.Lot) {
+	auc.shareChange(core.LotChangeAdded, lot, nil)
+	auc.notify.LotAdded(lot)
+}
+
 func (auc *auction) LotChanged(lot *core.Lot) {
-	syncLots(auc.pp, lot)
+	auc.pp.Sync(lot)
+	auc.shareChange(core.LotChangeChanged, lot, nil)
 	auc.notify.LotChanged(*lot)
 }
 
 func (auc *auction) LotChangedWithReceiver(lot *core.Lot, receiver *core.User) {
-	syncLots(auc.pp, lot)
+	auc.pp.Sync(lot)
+	auc.shareChange(core.LotChangeChanged, lot, receiver)
 	auc.notify.LotChangedWithReceiver(lot, receiver)
 }
 
 func (auc *auction) LotDeleted(lot *core.Lot) {
-	syncLots(auc.pp, lot)
+	auc.pp.Sync(lot)
+	auc.shareChange(core.LotChangeDeleted, lot, nil)
 	auc.notify.LotDeleted(lot)
 }
 
@@ -458,6 +625,15 @@ func (auc *auction) GetUser(act GetUser) error {
 
 		user.Groups = groups
 
+		if user.Level() == core.LevelUser {
+			score, err := service.NewReliabilityService(tx).Score(user.ID)
+			if err != nil {
+				logger.Error("get score failed", zap.Error(err))
+				return err
+			}
+			user.Score = &score
+		}
+
 		act.SetUser(user)
 
 		return nil
@@ -534,14 +710,19 @@ func (auc *auction) Lots(act Lots) error {
 			return err
 		}
 
-		syncLots(auc.pp, lots...)
+		unknown, err := auc.syncLots(tx, lots...)
+		if err != nil {
+			return err
+		}
 
 		restLots := make([]*core.Lot, 0, len(lots))
 		for _, lot := range lots {
 			if !act.Executor().CheckFilter(lot) {
 				continue
 			}
-			lot.UpdatePrice(act.Executor().ID)
+			if !unknown[lot.ID] {
+				lot.UpdatePrice(act.Executor().ID)
+			}
 			restLots = append(restLots, lot)
 		}
 
@@ -551,6 +732,21 @@ func (auc *auction) Lots(act Lots) error {
 	})
 }
 
+// createLot creates the lot on behalf of the executor, it is used for the
+// lots of templates and of bulk import.
+type createLot struct {
+	executor *core.User
+	lot      *core.Lot
+}
+
+func (act *createLot) Executor() *core.User {
+	return act.executor
+}
+
+func (act *createLot) GetLot() *core.Lot {
+	return act.lot
+}
+
 func (auc *auction) CreateLot(act CreateLot) error {
 	logger := auc.logger.Named("create_lot")
 
@@ -562,7 +758,19 @@ func (auc *auction) CreateLot(act CreateLot) error {
 			return lotTypeAccessDenied
 		}
 
-		rules, err := rule.Rules(lot.Rules, auc.config.RuleConfig)
+		svcGroup := service.NewGroupService(tx)
+
+		group, err := svcGroup.GroupByKey(lot.GroupKey)
+		if err != nil {
+			logger.Error("get group failed", zap.Error(err))
+			return err
+		}
+
+		if group != nil {
+			lot.GroupZone = &group.TimeZone
+		}
+
+		rules, err := rule.Rules(lot.Rules, lot.Location(), auc.config.RuleConfig, auc.clock)
 		if err != nil {
 			logger.Error("default rules failed", zap.Error(err))
 			return err
@@ -571,6 +779,14 @@ func (auc *auction) CreateLot(act CreateLot) error {
 		lot.User = act.Executor()
 		lot.UserID = lot.User.ID
 
+		if lot.ExpiresAt != nil && lot.PublishAt != nil &&
+			!lot.ExpiresAt.After(*lot.PublishAt) {
+			logger.Warn("lot expires before publication")
+			return lotExpiresInvalid
+		}
+
+		auc.defaultExpiry(lot)
+
 		svcLot := service.NewLotService(tx)
 
 		exLot, err := svcLot.LotByObject(lot.GroupKey, lot.ObjectID)
@@ -586,7 +802,7 @@ func (auc *auction) CreateLot(act CreateLot) error {
 		if err == nil {
 			lot.ID = exLot.ID
 			lot.DeletedAt = exLot.DeletedAt
-			if _, ok := auc.pp[lot.ID]; ok || (isManualBooked && lot.DeletedAt == nil) {
+			if _, ok := auc.pp.Get(lot.ID); ok || (isManualBooked && lot.DeletedAt == nil) {
 				logger.Warn("lot already exist")
 				return lotAlreadyExist
 			}
@@ -615,17 +831,11 @@ func (auc *auction) CreateLot(act CreateLot) error {
 				logger.Error("create bet failed", zap.Error(err))
 				return err
 			}
-		} else {
-			process, err := process.New(act.Executor(), lot, rules, auc.db, auc,
-				auc.cfgBackService, tx, nil)
-			if err != nil {
-				logger.Error("process failed", zap.Error(err))
+		} else if lot.Published(auc.now()) {
+			if err := auc.startProcess(tx, act.Executor(), lot, rules,
+				nil, 0); err != nil {
 				return err
 			}
-
-			auc.pp[lot.ID] = process
-
-			syncLots(auc.pp, lot)
 		}
 
 		svcHistory := service.NewHistoryService(tx)
@@ -636,9 +846,91 @@ func (auc *auction) CreateLot(act CreateLot) error {
 		}
 
 		if !isManualBooked {
-			auc.notify.LotAdded(lot)
+			auc.schedule(lot)
+			tx.AfterCommit(func() { auc.LotAdded(lot) })
+		}
+
+		return nil
+	})
+}
+
+// CreateLots creates every lot separately through CreateLot, so a failed
+// lot does not stop the others.
+func (auc *auction) CreateLots(act CreateLots) error {
+	logger := auc.logger.Named("create_lots")
+
+	for i, lot := range act.GetLots() {
+		if lot == nil {
+			continue
+		}
+
+		err := auc.CreateLot(&createLot{act.Executor(), lot})
+
+		switch err {
+		case nil:
+			act.LotCreated(i, lot)
+		case lotAlreadyExist:
+			act.LotExists(i)
+		default:
+			// only the messages of the public errors are shown to the user
+			if _, ok := err.(errors.CodeError); ok {
+				logger.Warn("create lot failed",
+					zap.Int("row", i+1),
+					zap.Error(err),
+				)
+				act.LotFailed(i, err)
+				continue
+			}
+			logger.Error("create lot failed",
+				zap.Int("row", i+1),
+				zap.Error(err),
+			)
+			act.LotFailed(i, lotCreateFailed)
+		}
+	}
+
+	return nil
+}
+
+// PreviewRules returns the timeline of the rules during the day in the
+// time zone of the lot, the lot is not created.
+func (auc *auction) PreviewRules(act PreviewRules) error {
+	logger := auc.logger.Named("preview_rules")
+
+	return auc.tx(func(tx *db.Tx) error {
+		lot := &core.Lot{TimeZone: act.Zone()}
+
+		if act.Group() != "" {
+			group, err := service.NewGroupService(tx).GroupByKey(act.Group())
+			if err != nil {
+				logger.Error("get group failed", zap.Error(err))
+				return err
+			}
+
+			if group == nil {
+				logger.Warn("group not found")
+				return groupNotFound
+			}
+
+			lot.GroupZone = &group.TimeZone
 		}
 
+		loc := lot.Location()
+
+		date := auc.now()
+
+		if act.Day() != "" {
+			d, err := time.ParseInLocation("2006-01-02", act.Day(), loc)
+			if err != nil {
+				logger.Warn("preview date invalid", zap.Error(err))
+				return previewDateInvalid
+			}
+			date = d
+		}
+
+		act.SetPreview(rule.NewPreview(act.GetRules(), date, loc,
+			auc.config.RuleConfig))
+
 		return nil
 	})
 }
@@ -660,9 +952,14 @@ func (auc *auction) GetLot(act GetLot) error {
 			return lotTypeAccessDenied
 		}
 
-		syncLots(auc.pp, lot)
+		unknown, err := auc.syncLots(tx, lot)
+		if err != nil {
+			return err
+		}
 
-		lot.UpdatePrice(act.Executor().ID)
+		if !unknown[lot.ID] {
+			lot.UpdatePrice(act.Executor().ID)
+		}
 
 		if lot.Complete != nil {
 			complete := &json_fileds.Complete{}
@@ -695,7 +992,10 @@ func (auc *auction) GetLot(act GetLot) error {
 func (auc *auction) UpdateLot(act UpdateLot) error {
 	logger := auc.logger.Named("update_lot")
 
-	return auc.tx(func(tx *db.Tx) error {
+	var prc process.Process
+	var rules []process.Rule
+
+	err := auc.tx(func(tx *db.Tx) error {
 		svc := service.NewLotService(tx)
 
 		lot, err := svc.Lot(act.LotID(), false)
@@ -709,16 +1009,55 @@ func (auc *auction) UpdateLot(act UpdateLot) error {
 			return lotTypeAccessDenied
 		}
 
+		rulesChanged := lot.TimeZone != act.GetLot().TimeZone ||
+			!equalRules(lot.Rules, act.GetLot().Rules)
+
 		lot.Rules = act.GetLot().Rules
 		lot.Object = act.GetLot().Object
 		lot.CompletedAt = act.GetLot().CompletedAt
 		lot.Urgent = act.GetLot().Urgent
+		lot.TimeZone = act.GetLot().TimeZone
+		lot.ReservePrice = act.GetLot().ReservePrice
+		lot.Fallback = act.GetLot().Fallback
+		lot.PublishAt = act.GetLot().PublishAt
+		lot.ExpiresAt = act.GetLot().ExpiresAt
+
+		if lot.ExpiresAt != nil && lot.PublishAt != nil &&
+			!lot.ExpiresAt.After(*lot.PublishAt) {
+			logger.Warn("lot expires before publication")
+			return lotExpiresInvalid
+		}
+
+		auc.defaultExpiry(lot)
+
+		rules, err = rule.Rules(lot.Rules, lot.Location(), auc.config.RuleConfig, auc.clock)
+		if err != nil {
+			logger.Warn("rules failed", zap.Error(err))
+			return err
+		}
+
+		// the process is only checked here and rescheduled after the commit,
+		// because it starts the rule in its own transaction of the lot
+		if rulesChanged {
+			prc, err = auc.lotProcess(lot.ID)
+			if err != nil {
+				return err
+			}
+			if prc != nil {
+				if err := prc.CheckReschedule(rules, lot); err != nil {
+					logger.Warn("check reschedule failed", zap.Error(err))
+					return err
+				}
+			}
+		}
 
 		if err := svc.SaveLot(lot); err != nil {
 			logger.Error("save lot failed", zap.Error(err))
 			return err
 		}
 
+		auc.schedule(lot)
+
 		svcHistory := service.NewHistoryService(tx)
 
 		if err := svcHistory.LotUpdated(lot.User.ID, lot); err != nil {
@@ -728,10 +1067,33 @@ func (auc *auction) UpdateLot(act UpdateLot) error {
 
 		act.SetLot(lot)
 
-		auc.LotChanged(lot)
+		tx.AfterCommit(func() { auc.LotChanged(lot) })
 
 		return nil
 	})
+	if err != nil || prc == nil {
+		return err
+	}
+
+	if err := prc.Reschedule(rules); err != nil {
+		logger.Error("reschedule failed", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// equalRules reports whether the rules have the same configs.
+func equalRules(a, b core.Rules) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
 }
 
 func (auc *auction) DeleteLot(act ActionLot) error {
@@ -751,26 +1113,10 @@ func (auc *auction) DeleteLot(act ActionLot) error {
 			return lotTypeAccessDenied
 		}
 
-		if err := svc.DeleteLot(lot); err != nil {
-			logger.Error("delete lot failed", zap.Error(err))
-			return err
-		}
-
-		svcBet := service.NewBetService(tx)
-
-		if err := svcBet.ClearBets(lot.ID); err != nil {
-			logger.Error("clear bets failed", zap.Error(err))
+		if err := auc.withdrawLot(tx, lot); err != nil {
 			return err
 		}
 
-		if process, ok := auc.pp[lot.ID]; ok {
-			if err := process.Stop(); err != nil {
-				logger.Error("process stop failed", zap.Error(err))
-				return err
-			}
-			delete(auc.pp, lot.ID)
-		}
-
 		svcHistory := service.NewHistoryService(tx)
 
 		if err := svcHistory.LotDeleted(lot.User.ID, lot); err != nil {
@@ -778,12 +1124,58 @@ func (auc *auction) DeleteLot(act ActionLot) error {
 			return err
 		}
 
-		auc.LotDeleted(lot)
+		tx.AfterCommit(func() { auc.LotDeleted(lot) })
 
 		return nil
 	})
 }
 
+// withdrawLot deletes the lot with its bets and stops its process.
+func (auc *auction) withdrawLot(tx *db.Tx, lot *core.Lot) error {
+	logger := auc.logger.Named("withdraw_lot").With(zap.Uint("lot_id", lot.ID))
+
+	svc := service.NewLotService(tx)
+
+	if err := svc.DeleteLot(lot); err != nil {
+		logger.Error("delete lot failed", zap.Error(err))
+		return err
+	}
+
+	auc.unschedule(lot.ID)
+
+	svcBet := service.NewBetService(tx)
+
+	if err := svcBet.ClearBets(lot.ID); err != nil {
+		logger.Error("clear bets failed", zap.Error(err))
+		return err
+	}
+
+	if process, ok := auc.pp.Get(lot.ID); ok {
+		if err := process.Stop(); err != nil {
+			logger.Error("process stop failed", zap.Error(err))
+			return err
+		}
+		auc.pp.Remove(process)
+	}
+
+	svcState := service.NewProcessStateService(tx)
+
+	if err := svcState.DeleteProcessState(lot.ID); err != nil {
+		logger.Error("delete process state failed", zap.Error(err))
+		return err
+	}
+
+	// the process run by another instance is stopped when it loses the lease
+	svcLease := service.NewLotLeaseService(tx)
+
+	if err := svcLease.DeleteLotLease(lot.ID); err != nil {
+		logger.Error("delete lot lease failed", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 func (auc *auction) History(act History) error {
 	logger := auc.logger.Named("history")
 
@@ -802,6 +1194,172 @@ func (auc *auction) History(act History) error {
 	})
 }
 
+func (auc *auction) LotEvents(act LotEvents) error {
+	logger := auc.logger.Named("lot_events")
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewLotEventService(tx)
+
+		events, err := svc.LotEvents(act.LotID())
+		if err != nil {
+			logger.Error("get lot events failed", zap.Error(err))
+			return err
+		}
+
+		act.SetLotEvents(events)
+
+		return nil
+	})
+}
+
+// Processes returns the states of the running processes. The processes
+// completed while they are listed are skipped.
+func (auc *auction) Processes(act Processes) error {
+	logger := auc.logger.Named("processes")
+
+	info := &core.ProcessesInfo{
+		Count:     auc.pp.Len(),
+		Rules:     auc.pp.CountByRule(),
+		Processes: []*core.ProcessState{},
+	}
+
+	for _, prc := range auc.pp.List() {
+		state, err := prc.State()
+		if err == process.ProcessNotFound {
+			continue
+		}
+		if err != nil {
+			logger.Error("process state failed",
+				zap.Uint("lot_id", prc.LotID()), zap.Error(err))
+			return err
+		}
+		info.Processes = append(info.Processes, state)
+	}
+
+	act.SetProcesses(info)
+
+	return nil
+}
+
+func (auc *auction) ProcessState(act ProcessState) error {
+	logger := auc.logger.Named("process_state")
+
+	state, err := auc.pp.State(act.LotID())
+	if err != nil {
+		logger.Warn("process state failed", zap.Error(err))
+		return err
+	}
+
+	act.SetProcessState(state)
+
+	return nil
+}
+
+// AdvanceClock moves the virtual clock, the timers of the processes
+// expired on the way are fired in the order of their deadlines.
+func (auc *auction) AdvanceClock(act AdvanceClock) error {
+	logger := auc.logger.Named("advance_clock")
+
+	c, ok := auc.clock.(*clock.Virtual)
+	if !ok {
+		logger.Warn("clock not virtual")
+		return clockNotVirtual
+	}
+
+	if act.NextTimer() {
+		c.Step()
+	} else {
+		if act.GetDuration() < 0 {
+			logger.Warn("clock duration invalid")
+			return clockDurationInvalid
+		}
+		c.Advance(act.GetDuration())
+	}
+
+	act.SetNow(auc.now())
+
+	return nil
+}
+
+// ReplayLot rebuilds the lot from its events. If the save is set the lot
+// and its bets are overwritten by the rebuilt state.
+func (auc *auction) ReplayLot(act ReplayLot) error {
+	logger := auc.logger.Named("replay_lot")
+
+	var lot *core.Lot
+
+	err := auc.tx(func(tx *db.Tx) error {
+		svc := service.NewLotService(tx)
+
+		var err error
+
+		lot, err = svc.Lot(act.LotID(), true)
+		if err != nil {
+			logger.Error("get lot failed", zap.Error(err))
+			return err
+		}
+
+		events, err := service.NewLotEventService(tx).LotEvents(lot.ID)
+		if err != nil {
+			logger.Error("get lot events failed", zap.Error(err))
+			return err
+		}
+
+		if len(events) == 0 {
+			logger.Warn("lot events not found")
+			return lotEventsNotFound
+		}
+
+		if err := core.ProjectLot(lot, events, act.Version()); err != nil {
+			logger.Error("project lot failed", zap.Error(err))
+			return err
+		}
+
+		if act.Save() {
+			if err := svc.SaveLot(lot); err != nil {
+				logger.Error("save lot failed", zap.Error(err))
+				return err
+			}
+
+			svcBet := service.NewBetService(tx)
+
+			if err := svcBet.ClearBets(lot.ID); err != nil {
+				logger.Error("clear bets failed", zap.Error(err))
+				return err
+			}
+
+			for _, bet := range lot.Bets {
+				if err := svcBet.RestoreBet(bet); err != nil {
+					logger.Error("restore bet failed", zap.Error(err))
+					return err
+				}
+			}
+
+			svcHistory := service.NewHistoryService(tx)
+
+			if err := svcHistory.LotReplayed(act.Executor().ID, lot); err != nil {
+				logger.Error("history lot replayed failed", zap.Error(err))
+				return err
+			}
+		}
+
+		lot.UpdatePrice(act.Executor().ID)
+
+		act.SetLot(lot)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if act.Save() {
+		auc.LotChanged(lot)
+	}
+
+	return nil
+}
+
 func (auc *auction) PlaceBet(act process.PlaceBet) error {
 	logger := auc.logger.Named("place_bet")
 	user, err := auc.User(act.Executor().Username)
@@ -810,12 +1368,57 @@ func (auc *auction) PlaceBet(act process.PlaceBet) error {
 		return err
 	}
 
-	if user.Blocked {
+	if user.IsBlocked(auc.now()) {
+		logger.Warn("user blocked")
+		return userBlocked
+	}
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.PlaceBet(act); err != nil {
+			return err
+		}
+		auc.LotChanged(act.GetLot())
+		return nil
+	}
+	return processNotFound
+}
+
+func (auc *auction) PlaceProxy(act process.PlaceProxy) error {
+	logger := auc.logger.Named("place_proxy")
+	user, err := auc.User(act.Executor().Username)
+	if err != nil {
+		logger.Error("get user failed")
+		return err
+	}
+
+	if user.IsBlocked(auc.now()) {
 		logger.Warn("user blocked")
 		return userBlocked
 	}
-	if process, ok := auc.pp[act.LotID()]; ok {
-		if err := process.PlaceBet(act); err != nil {
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.PlaceProxy(act); err != nil {
+			return err
+		}
+		auc.LotChanged(act.GetLot())
+		return nil
+	}
+	return processNotFound
+}
+
+func (auc *auction) CancelProxy(act process.Action) error {
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.CancelProxy(act); err != nil {
 			return err
 		}
 		auc.LotChanged(act.GetLot())
@@ -827,8 +1430,12 @@ func (auc *auction) PlaceBet(act process.PlaceBet) error {
 func (auc *auction) CancelBet(act process.Action) error {
 	logger := auc.logger.Named("cancel_lot")
 
-	if process, ok := auc.pp[act.LotID()]; ok {
-		if err := process.CancelBet(act); err != nil {
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.CancelBet(act); err != nil {
 			return err
 		}
 		auc.LotChanged(act.GetLot())
@@ -872,7 +1479,9 @@ func (auc *auction) CancelBet(act process.Action) error {
 			return err
 		}
 
-		if lot.BookedAt != nil {
+		booked := lot.BookedAt != nil
+
+		if booked {
 			lot.BookedAt = nil
 			lot.ManualBooked = false
 
@@ -889,30 +1498,29 @@ func (auc *auction) CancelBet(act process.Action) error {
 			}
 		}
 
-		rules, err := rule.Rules(lot.Rules, auc.config.RuleConfig)
+		rules, err := rule.Rules(lot.Rules, lot.Location(), auc.config.RuleConfig, auc.clock)
 		if err != nil {
 			logger.Error("rules failed", zap.Error(err))
 			return err
 		}
 
-		process, err := process.New(act.Executor(), lot, rules, auc.db, auc,
-			auc.cfgBackService, tx, nil)
-		if err != nil {
-			logger.Error("process failed", zap.Error(err))
+		if err := auc.startProcess(tx, act.Executor(), lot, rules,
+			nil, 0); err != nil {
 			return err
 		}
 
-		auc.pp[lot.ID] = process
-
-		syncLots(auc.pp, lot)
-
 		historySvc := service.NewHistoryService(tx)
 
-		if err := historySvc.BetCanceled(act.Executor().ID, lot); err != nil {
+		if booked {
+			err = historySvc.BookingCanceled(act.Executor().ID, lot)
+		} else {
+			err = historySvc.BetCanceled(act.Executor().ID, lot)
+		}
+		if err != nil {
 			return err
 		}
 
-		auc.LotChanged(lot)
+		tx.AfterCommit(func() { auc.LotChanged(lot) })
 
 		return nil
 	})
@@ -921,8 +1529,12 @@ func (auc *auction) CancelBet(act process.Action) error {
 func (auc *auction) ConfirmLot(act process.ConfirmLot) error {
 	logger := auc.logger.Named("confirm_lot")
 
-	if process, ok := auc.pp[act.LotID()]; ok {
-		if err := process.ConfirmLot(act); err != nil {
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.ConfirmLot(act); err != nil {
 			return err
 		}
 		auc.LotChangedWithReceiver(act.GetLot(), act.Executor())
@@ -973,7 +1585,7 @@ func (auc *auction) ConfirmLot(act process.ConfirmLot) error {
 
 		isConfirmEdit := lot.ConfirmedAt != nil
 
-		n := process.Now()
+		n := auc.now()
 
 		if !isConfirmEdit {
 			lot.ConfirmedAt = &n
@@ -1006,7 +1618,7 @@ func (auc *auction) ConfirmLot(act process.ConfirmLot) error {
 			}
 		}
 
-		auc.LotChangedWithReceiver(lot, act.Executor())
+		tx.AfterCommit(func() { auc.LotChangedWithReceiver(lot, act.Executor()) })
 
 		return nil
 	})
@@ -1045,6 +1657,11 @@ func (auc *auction) DeleteConfirmation(act ActionLot) error {
 			return err
 		}
 
+		var carrierID uint
+		if bet := lot.CurrentBet(); bet != nil {
+			carrierID = bet.UserID
+		}
+
 		svcBet := service.NewBetService(tx)
 
 		if err := svcBet.ClearBets(lot.ID); err != nil {
@@ -1062,33 +1679,28 @@ func (auc *auction) DeleteConfirmation(act ActionLot) error {
 			return err
 		}
 
-		rules, err := rule.Rules(lot.Rules, auc.config.RuleConfig)
+		rules, err := rule.Rules(lot.Rules, lot.Location(), auc.config.RuleConfig, auc.clock)
 		if err != nil {
 			logger.Error("rules failed", zap.Error(err))
 			return err
 		}
 
-		process, err := process.New(act.Executor(), lot, rules, auc.db, auc,
-			auc.cfgBackService, tx, nil)
-		if err != nil {
-			logger.Error("process failed", zap.Error(err))
+		if err := auc.startProcess(tx, act.Executor(), lot, rules,
+			nil, 0); err != nil {
 			return err
 		}
 
-		auc.pp[lot.ID] = process
-
-		syncLots(auc.pp, lot)
-
 		lot.UpdatePrice(act.Executor().ID)
 
 		historySvc := service.NewHistoryService(tx)
 
-		if err := historySvc.LotDeleteConfirmation(act.Executor().ID, lot); err != nil {
+		if err := historySvc.LotDeleteConfirmation(act.Executor().ID,
+			carrierID, lot); err != nil {
 			logger.Error("history lot delete confirmation failed", zap.Error(err))
 			return err
 		}
 
-		auc.LotChanged(lot)
+		tx.AfterCommit(func() { auc.LotChanged(lot) })
 
 		return err
 	})
@@ -1157,8 +1769,54 @@ func (auc *auction) CompleteLot(act CompleteLot) error {
 }
 
 func (auc *auction) AcceptBet(act process.AcceptBet) error {
-	if process, ok := auc.pp[act.LotID()]; ok {
-		if err := process.AcceptBet(act); err != nil {
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.AcceptBet(act); err != nil {
+			return err
+		}
+		auc.LotChanged(act.GetLot())
+		return nil
+	}
+	return processNotFound
+}
+
+func (auc *auction) PlaceReservation(act process.Action) error {
+	logger := auc.logger.Named("place_reservation")
+	user, err := auc.User(act.Executor().Username)
+	if err != nil {
+		logger.Error("get user failed")
+		return err
+	}
+
+	if user.IsBlocked(auc.now()) {
+		logger.Warn("user blocked")
+		return userBlocked
+	}
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.PlaceReservation(act,
+			auc.config.ReservationDuration); err != nil {
+			return err
+		}
+		auc.LotChanged(act.GetLot())
+		return nil
+	}
+	return processNotFound
+}
+
+func (auc *auction) CancelReservation(act process.Action) error {
+	prc, err := auc.lotProcess(act.LotID())
+	if err != nil {
+		return err
+	}
+	if prc != nil {
+		if err := prc.CancelReservation(act); err != nil {
 			return err
 		}
 		auc.LotChanged(act.GetLot())
@@ -1196,6 +1854,10 @@ func (auc *auction) GetBookingWindow(act GetBookingWindow) error {
 			return err
 		}
 
+		for i := range ld {
+			ld[i].TimeZone = lot.Location().String()
+		}
+
 		act.SetLoad(ld)
 
 		return nil
@@ -1272,7 +1934,7 @@ func (auc *auction) UpdateLotAct(cmd interfaces.EditActCommander) error {
 func (auc *auction) AutoBookingLot(cmd interfaces.AutoBookingCommander) error {
 	lot := cmd.GetLot()
 
-	now := time.Now().UTC()
+	now := auc.now()
 	lot.BookedAt = &now
 	return auc.CreateLot(cmd)
 }
@@ -1393,12 +2055,3 @@ func (auc *auction) tx(handler func(tx *db.Tx) error) error {
 
 	return nil
 }
-
-func syncLots(prcs map[uint]process.Process, lots ...*core.Lot) {
-	for i := 0; i < len(lots); i++ {
-		lot := lots[i]
-		if prc, ok := prcs[lot.ID]; ok {
-			prc.Sync(lot)
-		}
-	}
-}
@@ -0,0 +1,202 @@
+package auction
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+	"gitlab/nefco/auction/service"
+
+	"go.uber.org/zap"
+)
+
+// shareChange saves the change of the lot, so that the other instances
+// notify their clients about it. The clients connected to the instance are
+// notified by the instance itself. It is called after the change is
+// committed, so the change is saved in its own transaction.
+func (auc *auction) shareChange(kind string, lot *core.Lot, receiver *core.User) {
+	if !auc.leasing() {
+		return
+	}
+
+	logger := auc.logger.Named("share_change").With(zap.Uint("lot_id", lot.ID))
+
+	change, err := core.NewLotChange(kind, auc.config.LeaseConfig.Instance,
+		lot, receiver)
+	if err != nil {
+		logger.Error("lot change failed", zap.Error(err))
+		return
+	}
+
+	auc.tx(func(tx *db.Tx) error {
+		if err := service.NewLotChangeService(tx).AppendLotChange(change); err != nil {
+			logger.Error("append lot change failed", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+}
+
+// syncLots syncs the lots with their processes. The lots run by the other
+// instances get the state of their last shared change. It returns the lots
+// run by the other instances without the change, their state is unknown,
+// so their bets are cleared and their prices must not be updated.
+func (auc *auction) syncLots(tx *db.Tx, lots ...*core.Lot) (map[uint]bool, error) {
+	logger := auc.logger.Named("sync_lots")
+
+	auc.pp.Sync(lots...)
+
+	unknown := map[uint]bool{}
+
+	if !auc.leasing() {
+		return unknown, nil
+	}
+
+	instance := auc.config.LeaseConfig.Instance
+
+	lotIDs, err := service.NewLotLeaseService(tx).RemoteLots(instance)
+	if err != nil {
+		logger.Error("get remote lots failed", zap.Error(err))
+		return nil, err
+	}
+	if len(lotIDs) == 0 {
+		return unknown, nil
+	}
+
+	changes, err := service.NewLotChangeService(tx).RemoteLotChanges(instance)
+	if err != nil {
+		logger.Error("get remote lot changes failed", zap.Error(err))
+		return nil, err
+	}
+
+	remote := make(map[uint]*core.LotChange, len(lotIDs))
+	for _, lotID := range lotIDs {
+		remote[lotID] = nil
+	}
+	for _, change := range changes {
+		remote[change.LotID] = change
+	}
+
+	for _, lot := range lots {
+		change, ok := remote[lot.ID]
+		if !ok {
+			continue
+		}
+		if _, ok := auc.pp.Get(lot.ID); ok {
+			continue
+		}
+
+		if change != nil {
+			if err := change.Apply(lot); err == nil {
+				continue
+			}
+			logger.Error("apply lot change failed", zap.Uint("lot_id", lot.ID),
+				zap.Error(err))
+		}
+
+		lot.ClearBets()
+		unknown[lot.ID] = true
+	}
+
+	return unknown, nil
+}
+
+// changes notifies the clients of the instance about the changes of the
+// lots made by the other instances, while the instance runs. The changes
+// older than the lease TTL are deleted, except the last change of every lot.
+func (auc *auction) changes() {
+	logger := auc.logger.Named("changes")
+
+	cfg := auc.config.LeaseConfig
+
+	var lastID uint64
+
+	err := auc.tx(func(tx *db.Tx) error {
+		var err error
+		lastID, err = service.NewLotChangeService(tx).LastLotChange()
+		return err
+	})
+	if err != nil {
+		logger.Error("get last lot change failed", zap.Error(err))
+	}
+
+	cleaned := auc.now()
+
+	for {
+		timer := auc.clock.NewTimer(cfg.Poll)
+
+		select {
+		case <-timer.C():
+		case <-auc.done:
+			timer.Stop()
+			return
+		}
+
+		lastID = auc.applyChanges(lastID)
+
+		if auc.now().Sub(cleaned) < cfg.TTL {
+			continue
+		}
+
+		cleaned = auc.now()
+
+		auc.tx(func(tx *db.Tx) error {
+			err := service.NewLotChangeService(tx).
+				DeleteLotChangesBefore(cleaned.Add(-cfg.TTL))
+			if err != nil {
+				logger.Error("delete lot changes failed", zap.Error(err))
+			}
+			return err
+		})
+	}
+}
+
+// applyChanges notifies the clients about the changes after the change
+// and returns the ID of the last change read.
+func (auc *auction) applyChanges(lastID uint64) uint64 {
+	logger := auc.logger.Named("apply_changes")
+
+	auc.tx(func(tx *db.Tx) error {
+		changes, err := service.NewLotChangeService(tx).
+			LotChanges(lastID, auc.config.LeaseConfig.Instance)
+		if err != nil {
+			logger.Error("get lot changes failed", zap.Error(err))
+			return err
+		}
+
+		svc := service.NewLotService(tx)
+
+		for _, change := range changes {
+			lastID = change.ID
+
+			lot, err := svc.Lot(change.LotID, true)
+			if err == core.LotNotFound {
+				continue
+			}
+			if err != nil {
+				logger.Error("get lot failed", zap.Error(err))
+				return err
+			}
+
+			if err := change.Apply(lot); err != nil {
+				logger.Error("apply lot change failed", zap.Error(err))
+				continue
+			}
+
+			switch change.Kind {
+			case core.LotChangeAdded:
+				auc.notify.LotAdded(lot)
+			case core.LotChangeDeleted:
+				auc.notify.LotDeleted(lot)
+			default:
+				if receiver := change.Receiver(); receiver != nil {
+					auc.notify.LotChangedWithReceiver(lot, receiver)
+				} else {
+					auc.notify.LotChanged(*lot)
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return lastID
+}
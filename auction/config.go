@@ -1,13 +1,81 @@
 package auction
 
-import "gitlab/nefco/auction/process/rule"
+import (
+	"errors"
+	"gitlab/nefco/auction/process/rule"
+	"os"
+	"strings"
+	"time"
+)
 
 type Config struct {
-	RuleConfig *rule.Config `mapstructure:"rule"`
+	RuleConfig          *rule.Config  `mapstructure:"rule"`
+	EventSourcing       bool          `mapstructure:"event_sourcing"`
+	ReservationDuration time.Duration `mapstructure:"reservation_duration"`
+	TemplateGrace       time.Duration `mapstructure:"template_grace"`
+	LeaseConfig         *LeaseConfig  `mapstructure:"lease"`
+}
+
+// LeaseConfig is the config of the lot leases. The instance runs only the
+// processes of the lots leased by it, the commands of the other lots are
+// forwarded to the address of the instance which holds the lease. The
+// changes of the lots made by the other instances are polled to notify the
+// clients of the instance. The leases are disabled for the deployment of
+// the single instance.
+type LeaseConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Instance string        `mapstructure:"instance"`
+	Address  string        `mapstructure:"address"`
+	TTL      time.Duration `mapstructure:"ttl"`
+	Renew    time.Duration `mapstructure:"renew"`
+	Poll     time.Duration `mapstructure:"poll"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		RuleConfig: rule.DefaultConfig(),
+		RuleConfig:          rule.DefaultConfig(),
+		ReservationDuration: 15 * time.Minute,
+		TemplateGrace:       time.Hour,
+		LeaseConfig: &LeaseConfig{
+			Instance: hostname(),
+			Address:  "http://127.0.0.1:8080",
+			TTL:      30 * time.Second,
+			Renew:    10 * time.Second,
+			Poll:     time.Second,
+		},
+	}
+}
+
+func (cfg *LeaseConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(strings.TrimSpace(cfg.Instance)) == 0 {
+		return errors.New("instance not set")
+	}
+	if len(strings.TrimSpace(cfg.Address)) == 0 {
+		return errors.New("address not set")
+	}
+	if cfg.Renew <= 0 || cfg.Renew >= cfg.TTL {
+		return errors.New("renew must be positive and less than ttl")
+	}
+	if cfg.Poll <= 0 {
+		return errors.New("poll must be positive")
+	}
+	return nil
+}
+
+// deadline is the time after the renewal of the leases, when the processes
+// are stopped if the leases are not renewed again. It is before the leases
+// expire, so the processes stop before another instance takes them over.
+func (cfg *LeaseConfig) deadline() time.Duration {
+	return cfg.TTL - (cfg.TTL-cfg.Renew)/2
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "auction"
 	}
+	return name
 }
@@ -0,0 +1,460 @@
+package auction
+
+import (
+	"context"
+	"fmt"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+	"gitlab/nefco/auction/errors"
+	"gitlab/nefco/auction/process"
+	"gitlab/nefco/auction/service"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	auctionShutdown = errors.NewError("Auction shutdown", http.StatusServiceUnavailable)
+)
+
+// RemoteLot is returned by the commands of the lot whose process is run by
+// another instance of the auction, the command is forwarded to its address.
+type RemoteLot struct {
+	LotID    uint
+	Instance string
+	Address  string
+}
+
+func (err *RemoteLot) Error() string {
+	return fmt.Sprintf("lot %d is run by instance %s", err.LotID, err.Instance)
+}
+
+// leasing reports whether the lots are leased by the instances.
+func (auc *auction) leasing() bool {
+	return auc.config.LeaseConfig.Enabled
+}
+
+func (auc *auction) lease(lotID uint) *core.LotLease {
+	return &core.LotLease{
+		LotID:     lotID,
+		Instance:  auc.config.LeaseConfig.Instance,
+		Address:   auc.config.LeaseConfig.Address,
+		ExpiresAt: auc.now().Add(auc.config.LeaseConfig.TTL),
+	}
+}
+
+// acquire takes the lease of the lot for the instance. It returns RemoteLot
+// if the lot is leased by another instance.
+func (auc *auction) acquire(tx *db.Tx, lotID uint) error {
+	logger := auc.logger.Named("acquire").With(zap.Uint("lot_id", lotID))
+
+	if !auc.leasing() {
+		return nil
+	}
+
+	lease, err := service.NewLotLeaseService(tx).AcquireLotLease(auc.lease(lotID))
+	if err != nil {
+		logger.Error("acquire lot lease failed", zap.Error(err))
+		return err
+	}
+
+	if lease.Instance != auc.config.LeaseConfig.Instance {
+		logger.Debug("lot leased", zap.String("instance", lease.Instance))
+		return &RemoteLot{lease.LotID, lease.Instance, lease.Address}
+	}
+
+	return nil
+}
+
+// fence prolongs the lease of the lot in the transaction of its process, so
+// the process changes the lot only while the lease is held by the instance.
+// It returns core.LotLeaseLost if the lease is lost or expired.
+func (auc *auction) fence(tx *db.Tx, lotID uint) error {
+	logger := auc.logger.Named("fence").With(zap.Uint("lot_id", lotID))
+
+	ok, err := service.NewLotLeaseService(tx).RenewLotLease(auc.lease(lotID))
+	if err != nil {
+		logger.Error("renew lot lease failed", zap.Error(err))
+		return err
+	}
+
+	if !ok {
+		logger.Warn("lot lease lost")
+		return core.LotLeaseLost
+	}
+
+	return nil
+}
+
+// startProcess leases the lot and starts its process. The process is
+// registered after the transaction is committed and stopped if it is rolled
+// back. The start of the lot is locked till then, the lot which already
+// has the process is not started again.
+func (auc *auction) startProcess(tx *db.Tx, executor *core.User,
+	lot *core.Lot, rules []process.Rule,
+	startRule process.Rule, added time.Duration) error {
+	logger := auc.logger.Named("start_process").With(zap.Uint("lot_id", lot.ID))
+
+	if auc.closed() {
+		logger.Warn("auction shutdown")
+		return auctionShutdown
+	}
+
+	unlock := auc.pp.Lock(lot.ID)
+
+	if prc, ok := auc.pp.Get(lot.ID); ok {
+		unlock()
+		logger.Debug("process already started")
+		prc.Sync(lot)
+		return nil
+	}
+
+	if err := auc.acquire(tx, lot.ID); err != nil {
+		unlock()
+		return err
+	}
+
+	prc, err := process.New(executor, lot, rules, auc.store, auc, auc.clock,
+		process.NewTx(tx), startRule, added)
+	if err != nil {
+		unlock()
+		logger.Error("process failed", zap.Error(err))
+		return err
+	}
+
+	prc.Sync(lot)
+
+	tx.AfterCommit(func() {
+		defer unlock()
+		if auc.closed() || !auc.pp.Set(prc) {
+			logger.Warn("process not registered")
+			prc.Stop()
+		}
+	})
+
+	tx.AfterRollback(func() {
+		defer unlock()
+		prc.Stop()
+	})
+
+	return nil
+}
+
+// lotProcess returns the process of the lot run by the instance, or nil if
+// the lot has no process. If the lot is leased by another instance it
+// returns RemoteLot, if the lease is expired the process is taken over.
+func (auc *auction) lotProcess(lotID uint) (process.Process, error) {
+	logger := auc.logger.Named("lot_process").With(zap.Uint("lot_id", lotID))
+
+	if prc, ok := auc.pp.Get(lotID); ok {
+		return prc, nil
+	}
+
+	if !auc.leasing() {
+		return nil, nil
+	}
+
+	err := auc.tx(func(tx *db.Tx) error {
+		lease, err := service.NewLotLeaseService(tx).LotLease(lotID)
+		if err == core.LotLeaseNotFound {
+			return auc.takeover(tx, lotID)
+		}
+		if err != nil {
+			logger.Error("get lot lease failed", zap.Error(err))
+			return err
+		}
+
+		if lease.Instance != auc.config.LeaseConfig.Instance &&
+			!lease.Expired(auc.now()) {
+			return &RemoteLot{lease.LotID, lease.Instance, lease.Address}
+		}
+
+		return auc.takeover(tx, lotID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if prc, ok := auc.pp.Get(lotID); ok {
+		return prc, nil
+	}
+
+	return nil, nil
+}
+
+// takeover restores the process of the lot from its saved state, if the
+// lot is not leased by another instance. The state of the lot which may no
+// longer run is deleted.
+func (auc *auction) takeover(tx *db.Tx, lotID uint) error {
+	logger := auc.logger.Named("takeover").With(zap.Uint("lot_id", lotID))
+
+	if _, ok := auc.pp.Get(lotID); ok {
+		return nil
+	}
+
+	svcState := service.NewProcessStateService(tx)
+
+	state, err := svcState.ProcessState(lotID)
+	if err == core.ProcessStateNotFound {
+		return nil
+	}
+	if err != nil {
+		logger.Error("get process state failed", zap.Error(err))
+		return err
+	}
+
+	lot, err := service.NewLotService(tx).Lot(lotID, false)
+	if err != nil && err != core.LotNotFound {
+		logger.Error("get lot failed", zap.Error(err))
+		return err
+	}
+
+	n := auc.now()
+
+	if lot == nil || !lot.Published(n) || lot.Expired(n) ||
+		lot.ManualBooked || lot.ConfirmedAt != nil {
+		logger.Info("stale process state")
+		if err := svcState.DeleteProcessState(lotID); err != nil {
+			logger.Error("delete process state failed", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+
+	if err := auc.restoreLot(tx, auc.runner, lot, state); err != nil {
+		return err
+	}
+
+	logger.Info("lot taken over")
+
+	return nil
+}
+
+// leases renews the leases of the running processes and takes over the
+// lots with expired leases, while the instance runs. The processes whose
+// leases are lost are stopped without stopping their rules, the rules are
+// continued by the new owner. If the leases are not renewed, the processes
+// are stopped by the timer before the leases expire, even if the renewal
+// hangs.
+func (auc *auction) leases() {
+	drop := auc.after(auc.config.LeaseConfig.deadline(), auc.dropProcesses)
+
+	for {
+		timer := auc.clock.NewTimer(auc.config.LeaseConfig.Renew)
+
+		select {
+		case <-timer.C():
+		case <-auc.done:
+			timer.Stop()
+			drop.stop()
+			return
+		}
+
+		// the leases are prolonged from the time after this one
+		renewed := auc.now()
+
+		if err := auc.renewLeases(); err != nil {
+			continue
+		}
+
+		drop.stop()
+		drop = auc.after(
+			renewed.Add(auc.config.LeaseConfig.deadline()).Sub(auc.now()),
+			auc.dropProcesses)
+
+		auc.takeoverLeases()
+	}
+}
+
+func (auc *auction) renewLeases() error {
+	logger := auc.logger.Named("renew_leases")
+
+	var lost []process.Process
+
+	err := auc.tx(func(tx *db.Tx) error {
+		svc := service.NewLotLeaseService(tx)
+
+		lost = nil
+
+		for _, prc := range auc.pp.List() {
+			ok, err := svc.RenewLotLease(auc.lease(prc.LotID()))
+			if err != nil {
+				logger.Error("renew lot lease failed", zap.Error(err))
+				return err
+			}
+			if !ok {
+				lost = append(lost, prc)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, prc := range lost {
+		logger.Warn("lot lease lost", zap.Uint("lot_id", prc.LotID()))
+		auc.dropProcess(prc)
+	}
+
+	return nil
+}
+
+func (auc *auction) takeoverLeases() {
+	logger := auc.logger.Named("takeover_leases")
+
+	var lotIDs []uint
+
+	err := auc.tx(func(tx *db.Tx) error {
+		svc := service.NewLotLeaseService(tx)
+
+		if err := svc.DeleteExpiredLotLeases(); err != nil {
+			logger.Error("delete expired lot leases failed", zap.Error(err))
+			return err
+		}
+
+		var err error
+
+		lotIDs, err = svc.UnleasedLots()
+		if err != nil {
+			logger.Error("get unleased lots failed", zap.Error(err))
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	for _, lotID := range lotIDs {
+		err := auc.tx(func(tx *db.Tx) error {
+			return auc.takeover(tx, lotID)
+		})
+		if _, ok := err.(*RemoteLot); ok {
+			continue
+		}
+		if err != nil {
+			logger.Error("takeover failed",
+				zap.Uint("lot_id", lotID), zap.Error(err))
+			continue
+		}
+
+		if prc, ok := auc.pp.Get(lotID); ok {
+			auc.refresh(prc)
+		}
+	}
+}
+
+// refresh notifies the clients of the lot taken over by the instance.
+func (auc *auction) refresh(prc process.Process) {
+	logger := auc.logger.Named("refresh").With(zap.Uint("lot_id", prc.LotID()))
+
+	auc.tx(func(tx *db.Tx) error {
+		lot, err := service.NewLotService(tx).Lot(prc.LotID(), false)
+		if err != nil {
+			logger.Error("get lot failed", zap.Error(err))
+			return err
+		}
+
+		prc.Sync(lot)
+
+		tx.AfterCommit(func() { auc.LotChanged(lot) })
+
+		return nil
+	})
+}
+
+// dropProcess stops the process leased by another instance.
+func (auc *auction) dropProcess(prc process.Process) {
+	prc.Stop()
+	auc.pp.Remove(prc)
+}
+
+// dropProcesses stops all processes, when their leases may be taken over.
+func (auc *auction) dropProcesses() error {
+	logger := auc.logger.Named("drop_processes")
+
+	pp := auc.pp.List()
+	if len(pp) == 0 {
+		return nil
+	}
+
+	logger.Warn("lot leases not renewed", zap.Int("count", len(pp)))
+
+	for _, prc := range pp {
+		auc.dropProcess(prc)
+	}
+
+	return nil
+}
+
+func (auc *auction) closed() bool {
+	select {
+	case <-auc.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown stops the processes of the instance after the handlers being
+// run, without stopping their rules, and releases their leases, so that
+// another instance takes them over at once. No process is started and no
+// scheduled handler is run after it. If the context is done before the
+// processes stop, the leases of the processes not stopped are left to
+// expire and the error of the context is returned.
+func (auc *auction) Shutdown(ctx context.Context) error {
+	logger := auc.logger.Named("shutdown")
+
+	auc.shutdownOnce.Do(func() {
+		close(auc.done)
+	})
+
+	auc.unscheduleAll()
+
+	pp := auc.pp.List()
+
+	var stopped []process.Process
+	var result error
+
+	for _, prc := range pp {
+		auc.pp.Remove(prc)
+
+		if err := prc.Shutdown(ctx); err != nil {
+			logger.Warn("process not stopped",
+				zap.Uint("lot_id", prc.LotID()), zap.Error(err))
+			result = err
+			continue
+		}
+
+		stopped = append(stopped, prc)
+	}
+
+	logger.Info("processes stopped",
+		zap.Int("count", len(stopped)), zap.Int("total", len(pp)))
+
+	if !auc.leasing() {
+		return result
+	}
+
+	err := auc.tx(func(tx *db.Tx) error {
+		svc := service.NewLotLeaseService(tx)
+
+		for _, prc := range stopped {
+			if err := svc.ReleaseLotLease(auc.lease(prc.LotID())); err != nil {
+				logger.Error("release lot lease failed", zap.Error(err))
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
@@ -0,0 +1,258 @@
+package auction
+
+import (
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/core/object"
+	"gitlab/nefco/auction/db"
+	"gitlab/nefco/auction/errors"
+	"gitlab/nefco/auction/process/rule"
+	"gitlab/nefco/auction/service"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	lotExpiresInvalid = errors.BadRequest("Lot expires before publication")
+)
+
+const (
+	jobPublish = "publish"
+	jobExpire  = "expire"
+)
+
+// lotJob is the key of the scheduled handler of the lot.
+type lotJob struct {
+	lotID uint
+	kind  string
+}
+
+// job is the handler run by the timer unless it is stopped.
+type job struct {
+	timer  clock.Timer
+	cancel chan struct{}
+	once   sync.Once
+}
+
+func (j *job) stop() {
+	j.once.Do(func() {
+		j.timer.Stop()
+		close(j.cancel)
+	})
+}
+
+// schedule starts the timers of the publication and the expiry of the lot,
+// replacing the timers started for its previous version. The timers check
+// the lot again when they fire, because they may fire before the change of
+// the lot is committed.
+func (auc *auction) schedule(lot *core.Lot) {
+	n := auc.now()
+	lotID := lot.ID
+
+	if !lot.Published(n) {
+		auc.scheduleJob(lotJob{lotID, jobPublish}, lot.PublishAt.Sub(n),
+			func() error {
+				return auc.publish(lotID)
+			})
+	} else {
+		auc.unscheduleJob(lotJob{lotID, jobPublish})
+	}
+
+	if lot.ExpiresAt != nil {
+		auc.scheduleJob(lotJob{lotID, jobExpire}, lot.ExpiresAt.Sub(n),
+			func() error {
+				return auc.expire(lotID)
+			})
+	} else {
+		auc.unscheduleJob(lotJob{lotID, jobExpire})
+	}
+}
+
+// unschedule stops the timers of the deleted lot.
+func (auc *auction) unschedule(lotID uint) {
+	auc.unscheduleJob(lotJob{lotID, jobPublish})
+	auc.unscheduleJob(lotJob{lotID, jobExpire})
+}
+
+func (auc *auction) scheduleJob(key lotJob, d time.Duration,
+	handler func() error) {
+	auc.jobsMu.Lock()
+	defer auc.jobsMu.Unlock()
+
+	if j, ok := auc.jobs[key]; ok {
+		j.stop()
+	}
+
+	var j *job
+	j = auc.after(d, func() error {
+		auc.jobsMu.Lock()
+		if auc.jobs[key] == j {
+			delete(auc.jobs, key)
+		}
+		auc.jobsMu.Unlock()
+
+		return handler()
+	})
+
+	auc.jobs[key] = j
+}
+
+func (auc *auction) unscheduleJob(key lotJob) {
+	auc.jobsMu.Lock()
+	defer auc.jobsMu.Unlock()
+
+	if j, ok := auc.jobs[key]; ok {
+		j.stop()
+		delete(auc.jobs, key)
+	}
+}
+
+// unscheduleAll stops the scheduled handlers of all lots.
+func (auc *auction) unscheduleAll() {
+	auc.jobsMu.Lock()
+	defer auc.jobsMu.Unlock()
+
+	for key, j := range auc.jobs {
+		j.stop()
+		delete(auc.jobs, key)
+	}
+}
+
+// after runs the handler in its own goroutine after the duration, unless
+// the job is stopped or the auction is shut down.
+func (auc *auction) after(d time.Duration, handler func() error) *job {
+	logger := auc.logger.Named("schedule")
+
+	j := &job{
+		timer:  auc.clock.NewTimer(d),
+		cancel: make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-j.timer.C():
+		case <-j.cancel:
+			return
+		case <-auc.done:
+			j.stop()
+			return
+		}
+		if err := handler(); err != nil {
+			logger.Error("scheduled handler failed", zap.Error(err))
+		}
+	}()
+
+	return j
+}
+
+// defaultExpiry sets the expiry of the lot without one to the deadline of
+// its object, e.g. the first loading date of the trip. The deadline is
+// skipped if it is passed or the lot is published after it.
+func (auc *auction) defaultExpiry(lot *core.Lot) {
+	if lot.ExpiresAt != nil {
+		return
+	}
+
+	data, ok := lot.Object.Data.(object.ObjectDeadline)
+	if !ok {
+		return
+	}
+
+	deadline, ok := data.Deadline()
+	if !ok || !deadline.After(auc.now()) ||
+		(lot.PublishAt != nil && !deadline.After(*lot.PublishAt)) {
+		return
+	}
+
+	lot.ExpiresAt = &deadline
+}
+
+// publish starts the process of the lot at its publication time.
+func (auc *auction) publish(lotID uint) error {
+	logger := auc.logger.Named("publish").With(zap.Uint("lot_id", lotID))
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewLotService(tx)
+
+		lot, err := svc.Lot(lotID, false)
+		if err == core.LotNotFound {
+			return nil
+		}
+		if err != nil {
+			logger.Error("get lot failed", zap.Error(err))
+			return err
+		}
+
+		n := auc.now()
+
+		if _, ok := auc.pp.Get(lot.ID); ok || !lot.Published(n) ||
+			lot.Expired(n) || lot.ManualBooked {
+			return nil
+		}
+
+		rules, err := rule.Rules(lot.Rules, lot.Location(), auc.config.RuleConfig, auc.clock)
+		if err != nil {
+			logger.Error("rules failed", zap.Error(err))
+			return err
+		}
+
+		err = auc.startProcess(tx, lot.User, lot, rules, nil, 0)
+		if _, ok := err.(*RemoteLot); ok {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		svcHistory := service.NewHistoryService(tx)
+
+		if err := svcHistory.LotPublished(core.RootUserID, lot); err != nil {
+			logger.Error("history lot published failed", zap.Error(err))
+			return err
+		}
+
+		tx.AfterCommit(func() { auc.LotAdded(lot) })
+
+		return nil
+	})
+}
+
+// expire withdraws the lot which is not booked till its expiry time.
+func (auc *auction) expire(lotID uint) error {
+	logger := auc.logger.Named("expire").With(zap.Uint("lot_id", lotID))
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewLotService(tx)
+
+		lot, err := svc.Lot(lotID, false)
+		if err == core.LotNotFound {
+			return nil
+		}
+		if err != nil {
+			logger.Error("get lot failed", zap.Error(err))
+			return err
+		}
+
+		if !lot.Expired(auc.now()) || lot.BookedAt != nil ||
+			lot.ConfirmedAt != nil || lot.CompletedAt != nil {
+			return nil
+		}
+
+		if err := auc.withdrawLot(tx, lot); err != nil {
+			return err
+		}
+
+		svcHistory := service.NewHistoryService(tx)
+
+		if err := svcHistory.LotExpired(core.RootUserID, lot); err != nil {
+			logger.Error("history lot expired failed", zap.Error(err))
+			return err
+		}
+
+		tx.AfterCommit(func() { auc.LotDeleted(lot) })
+
+		return nil
+	})
+}
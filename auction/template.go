@@ -0,0 +1,393 @@
+package auction
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+	"gitlab/nefco/auction/errors"
+	"gitlab/nefco/auction/process"
+	"gitlab/nefco/auction/process/rule"
+	"gitlab/nefco/auction/service"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	templateAccessDenied      = errors.Forbidden("Template access denied")
+	templateRecurrenceInvalid = errors.BadRequest("Template recurrence invalid")
+)
+
+type ActionTemplate interface {
+	Action
+	TemplateID() uint
+}
+
+type Templates interface {
+	Action
+	SetTemplates(templates []*core.LotTemplate)
+}
+
+type CreateTemplate interface {
+	Action
+	GetTemplate() *core.LotTemplate
+}
+
+type GetTemplate interface {
+	ActionTemplate
+	SetTemplate(template *core.LotTemplate)
+}
+
+type UpdateTemplate interface {
+	ActionTemplate
+	GetTemplate() *core.LotTemplate
+	SetTemplate(template *core.LotTemplate)
+}
+
+func (auc *auction) Templates(act Templates) error {
+	logger := auc.logger.Named("templates")
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewTemplateService(tx)
+
+		templates, err := svc.Templates()
+		if err != nil {
+			logger.Error("get templates failed", zap.Error(err))
+			return err
+		}
+
+		res := make([]*core.LotTemplate, 0, len(templates))
+
+		for _, template := range templates {
+			if act.Executor().CheckGroup(template.Lot.GroupKey) {
+				res = append(res, template)
+			}
+		}
+
+		act.SetTemplates(res)
+
+		return nil
+	})
+}
+
+func (auc *auction) CreateTemplate(act CreateTemplate) error {
+	logger := auc.logger.Named("create_template")
+
+	return auc.tx(func(tx *db.Tx) error {
+		template := act.GetTemplate()
+
+		if !act.Executor().CheckGroup(template.Lot.GroupKey) {
+			logger.Warn("template access denied")
+			return templateAccessDenied
+		}
+
+		template.UserID = act.Executor().ID
+		template.LastRunAt = nil
+
+		if _, _, err := auc.recurrence(tx, template); err != nil {
+			return err
+		}
+
+		svc := service.NewTemplateService(tx)
+
+		if err := svc.CreateTemplate(template); err != nil {
+			logger.Error("create template failed", zap.Error(err))
+			return err
+		}
+
+		return auc.scheduleTemplate(tx, template)
+	})
+}
+
+func (auc *auction) GetTemplate(act GetTemplate) error {
+	logger := auc.logger.Named("get_template")
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewTemplateService(tx)
+
+		template, err := svc.Template(act.TemplateID())
+		if err != nil {
+			logger.Warn("get template failed", zap.Error(err))
+			return err
+		}
+
+		if !act.Executor().CheckGroup(template.Lot.GroupKey) {
+			logger.Warn("template access denied")
+			return templateAccessDenied
+		}
+
+		act.SetTemplate(template)
+
+		return nil
+	})
+}
+
+func (auc *auction) UpdateTemplate(act UpdateTemplate) error {
+	logger := auc.logger.Named("update_template")
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewTemplateService(tx)
+
+		template, err := svc.Template(act.TemplateID())
+		if err != nil {
+			logger.Warn("get template failed", zap.Error(err))
+			return err
+		}
+
+		if !act.Executor().CheckGroup(template.Lot.GroupKey) ||
+			!act.Executor().CheckGroup(act.GetTemplate().Lot.GroupKey) {
+			logger.Warn("template access denied")
+			return templateAccessDenied
+		}
+
+		template.Name = act.GetTemplate().Name
+		template.Lot = act.GetTemplate().Lot
+		template.Recurrence = act.GetTemplate().Recurrence
+		template.StartAt = act.GetTemplate().StartAt
+		template.NextObjectID = act.GetTemplate().NextObjectID
+
+		if _, _, err := auc.recurrence(tx, template); err != nil {
+			return err
+		}
+
+		if err := svc.SaveTemplate(template); err != nil {
+			logger.Error("save template failed", zap.Error(err))
+			return err
+		}
+
+		act.SetTemplate(template)
+
+		return auc.scheduleTemplate(tx, template)
+	})
+}
+
+func (auc *auction) DeleteTemplate(act ActionTemplate) error {
+	logger := auc.logger.Named("delete_template")
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewTemplateService(tx)
+
+		template, err := svc.Template(act.TemplateID())
+		if err != nil {
+			logger.Warn("get template failed", zap.Error(err))
+			return err
+		}
+
+		if !act.Executor().CheckGroup(template.Lot.GroupKey) {
+			logger.Warn("template access denied")
+			return templateAccessDenied
+		}
+
+		if err := svc.DeleteTemplate(template); err != nil {
+			logger.Error("delete template failed", zap.Error(err))
+			return err
+		}
+
+		return nil
+	})
+}
+
+// restoreTemplates starts the timers of all templates.
+func (auc *auction) restoreTemplates(tx *db.Tx) error {
+	logger := auc.logger.Named("restore_templates")
+
+	templates, err := service.NewTemplateService(tx).Templates()
+	if err != nil {
+		logger.Error("get templates failed", zap.Error(err))
+		return err
+	}
+
+	for _, template := range templates {
+		if err := auc.scheduleTemplate(tx, template); err != nil {
+			logger.Error("schedule template failed",
+				zap.Uint("template_id", template.ID), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// recurrence returns the recurrence of the template and the time zone of
+// its lots. It also checks that the rules of the lots are valid.
+func (auc *auction) recurrence(tx *db.Tx,
+	template *core.LotTemplate) (process.Recurrence, *time.Location, error) {
+	logger := auc.logger.Named("recurrence")
+
+	lot := &core.Lot{TimeZone: template.Lot.TimeZone}
+
+	group, err := service.NewGroupService(tx).GroupByKey(template.Lot.GroupKey)
+	if err != nil {
+		logger.Error("get group failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if group != nil {
+		lot.GroupZone = &group.TimeZone
+	}
+
+	if _, err := rule.Rules(template.Lot.Rules, lot.Location(),
+		auc.config.RuleConfig, auc.clock); err != nil {
+		logger.Warn("rules failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	r, err := process.ParseRecurrence(&template.Recurrence, lot.Location())
+	if err != nil {
+		logger.Warn("parse recurrence failed", zap.Error(err))
+		return nil, nil, templateRecurrenceInvalid
+	}
+
+	return r, lot.Location(), nil
+}
+
+// nextRun returns the time of the next lot of the template at the time t.
+// If several lots were missed, for example while the server was down, only
+// the last of them is created and only if it was missed within the grace,
+// otherwise the next lot is planned after t.
+func nextRun(r process.Recurrence, template *core.LotTemplate,
+	t time.Time, grace time.Duration) time.Time {
+	after := template.StartAt.Add(-time.Nanosecond)
+	if template.LastRunAt != nil && !template.LastRunAt.Before(after) {
+		after = *template.LastRunAt
+	}
+
+	next := r.Next(after)
+
+	for !next.IsZero() {
+		following := r.Next(next)
+		if following.IsZero() || following.After(t) {
+			break
+		}
+		next = following
+	}
+
+	if !next.IsZero() && next.Before(t.Add(-grace)) {
+		next = r.Next(t)
+	}
+
+	return next
+}
+
+// scheduleTemplate starts the timer of the next lot of the template. The
+// timer checks the template again when it fires, so the template may be
+// changed or deleted meanwhile.
+func (auc *auction) scheduleTemplate(tx *db.Tx, template *core.LotTemplate) error {
+	r, _, err := auc.recurrence(tx, template)
+	if err != nil {
+		return err
+	}
+
+	n := auc.now()
+
+	at := nextRun(r, template, n, auc.config.TemplateGrace)
+	if at.IsZero() {
+		return nil
+	}
+
+	templateID := template.ID
+
+	auc.after(at.Sub(n), func() error {
+		return auc.runTemplate(templateID, at)
+	})
+
+	return nil
+}
+
+// runTemplate creates the lot of the template planned at the time.
+func (auc *auction) runTemplate(templateID uint, at time.Time) error {
+	logger := auc.logger.Named("run_template").With(
+		zap.Uint("template_id", templateID),
+		zap.Time("at", at),
+	)
+
+	var act *createLot
+
+	err := auc.tx(func(tx *db.Tx) error {
+		template, err := service.NewTemplateService(tx).Template(templateID)
+		if err == core.TemplateNotFound {
+			return nil
+		}
+		if err != nil {
+			logger.Error("get template failed", zap.Error(err))
+			return err
+		}
+
+		r, loc, err := auc.recurrence(tx, template)
+		if err != nil {
+			return err
+		}
+
+		if !nextRun(r, template, at, auc.config.TemplateGrace).Equal(at) {
+			logger.Debug("template run outdated")
+			return nil
+		}
+
+		svcUser := service.NewUserService(tx)
+
+		executor, err := svcUser.User(template.UserID)
+		if err != nil {
+			logger.Error("get user failed", zap.Error(err))
+			return err
+		}
+
+		executor.Groups, err = svcUser.GroupsByUser(executor.ID)
+		if err != nil {
+			logger.Error("get groups by user failed", zap.Error(err))
+			return err
+		}
+
+		lot, err := template.NewLot(days(template.StartAt, at, loc))
+		if err != nil {
+			logger.Error("template lot failed", zap.Error(err))
+			return err
+		}
+
+		act = &createLot{executor, lot}
+
+		return nil
+	})
+	if err != nil || act == nil {
+		return err
+	}
+
+	created := true
+
+	if err := auc.CreateLot(act); err != nil {
+		logger.Error("create lot failed", zap.Error(err))
+		created = false
+	}
+
+	return auc.tx(func(tx *db.Tx) error {
+		svc := service.NewTemplateService(tx)
+
+		template, err := svc.Template(templateID)
+		if err == core.TemplateNotFound {
+			return nil
+		}
+		if err != nil {
+			logger.Error("get template failed", zap.Error(err))
+			return err
+		}
+
+		template.LastRunAt = &at
+		if created {
+			template.NextObjectID++
+		}
+
+		if err := svc.SaveTemplate(template); err != nil {
+			logger.Error("save template failed", zap.Error(err))
+			return err
+		}
+
+		return auc.scheduleTemplate(tx, template)
+	})
+}
+
+// days returns the number of calendar days from the date of start to the
+// date of end in the location.
+func days(start, end time.Time, loc *time.Location) int {
+	y, m, d := start.In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = end.In(loc).Date()
+	to := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from) / (24 * time.Hour))
+}
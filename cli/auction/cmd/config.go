@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"gitlab/nefco/auction/auction"
+	"gitlab/nefco/auction/clock"
 	"gitlab/nefco/auction/db"
 	"gitlab/nefco/auction/server"
 	"gitlab/nefco/auction/service"
@@ -19,12 +20,17 @@ type config struct {
 	AuctionConfig         *auction.Config                `mapstructure:"auction"`
 	FeedbackServiceConfig *service.ConfigFeedbackService `mapstructure:"feedback_service"`
 	BackServiceConfig     *service.ConfigBackService     `mapstructure:"back_service"`
+	ClockConfig           *clock.Config                  `mapstructure:"clock"`
+	ReliabilityConfig     *service.ConfigReliability     `mapstructure:"reliability"`
 }
 
 func (cfg *config) validate() error {
 	if err := cfg.DBConfig.Validate(); err != nil {
 		return errors.New("db config: " + err.Error())
 	}
+	if err := cfg.AuctionConfig.LeaseConfig.Validate(); err != nil {
+		return errors.New("lease config: " + err.Error())
+	}
 	return nil
 }
 
@@ -38,6 +44,8 @@ func defaultConfig() *config {
 		AuctionConfig:         auction.DefaultConfig(),
 		FeedbackServiceConfig: service.DefaultConfigFeedbackService(),
 		BackServiceConfig:     service.DefaultConfigBackService(),
+		ClockConfig:           clock.DefaultConfig(),
+		ReliabilityConfig:     service.DefaultConfigReliability(),
 	}
 }
 
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"gitlab/nefco/auction/auction"
 	"gitlab/nefco/auction/db"
 	"gitlab/nefco/auction/server"
 	"gitlab/nefco/auction/service"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"go.uber.org/zap"
 
@@ -107,7 +111,16 @@ func run(cmd *cobra.Command, args []string) error {
 		fmt.Sprintf("using config file: %s\n\n", viper.ConfigFileUsed()),
 	)
 
-	db, err := db.New(cfg.DBConfig)
+	c, err := cfg.ClockConfig.Clock()
+	if err != nil {
+		zap.L().Error("error", zap.Error(err))
+		return err
+	}
+
+	service.EnableLotEvents(cfg.AuctionConfig.EventSourcing)
+	service.SetConfigReliability(cfg.ReliabilityConfig)
+
+	db, err := db.New(cfg.DBConfig, c)
 	if err != nil {
 		zap.L().Error("error", zap.Error(err))
 		return err
@@ -132,19 +145,47 @@ func run(cmd *cobra.Command, args []string) error {
 	// 	}
 	// }
 
-	notify := service.NewNotifyService()
+	notify := service.NewNotifyService(c)
 
 	feedbackSvc := service.NewFeedbackService(cfg.FeedbackServiceConfig)
 
 	auction := auction.New(cfg.AuctionConfig, db, notify, feedbackSvc,
-		cfg.BackServiceConfig)
+		cfg.BackServiceConfig, c)
 
 	if err := auction.Restore(user); err != nil {
 		zap.L().Error("error", zap.Error(err))
 		return err
 	}
 
-	if err := server.NewServer(cfg.ServerConfig, auction, notify); err != nil {
+	srv := server.NewServer(cfg.ServerConfig, auction, notify)
+
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- srv.Start()
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-errc:
+		zap.L().Error("error", zap.Error(err))
+		return err
+	case s := <-sig:
+		zap.L().Info("shutdown", zap.Stringer("signal", s))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		cfg.ServerConfig.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		zap.L().Error("error", zap.Error(err))
+		return err
+	}
+
+	if err := <-errc; err != nil && err != http.ErrServerClosed {
 		zap.L().Error("error", zap.Error(err))
 		return err
 	}
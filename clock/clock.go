@@ -0,0 +1,83 @@
+package clock
+
+import (
+	"time"
+)
+
+// Clock is the source of the time for processes, rules, timelines and
+// services. It is passed to them by the auction, so that a lot may be run
+// in the accelerated or virtual time. Now returns the time in UTC.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	Sleep(d time.Duration)
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realClock struct{}
+
+// Real returns the clock of the system time.
+func Real() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+type accelerated struct {
+	start  time.Time
+	origin time.Time
+	factor float64
+}
+
+// Accelerated returns the clock started at start which runs factor times
+// faster than the system time.
+func Accelerated(start time.Time, factor float64) Clock {
+	return &accelerated{
+		start:  start,
+		origin: time.Now(),
+		factor: factor,
+	}
+}
+
+func (c *accelerated) Now() time.Time {
+	elapsed := float64(time.Since(c.origin)) * c.factor
+	return c.start.Add(time.Duration(elapsed)).UTC()
+}
+
+func (c *accelerated) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(c.real(d))}
+}
+
+func (c *accelerated) Sleep(d time.Duration) {
+	time.Sleep(c.real(d))
+}
+
+func (c *accelerated) real(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / c.factor)
+}
@@ -0,0 +1,58 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ModeReal        = "real"
+	ModeAccelerated = "accelerated"
+	ModeVirtual     = "virtual"
+)
+
+type Config struct {
+	Mode   string  `mapstructure:"mode"`
+	Start  string  `mapstructure:"start"`
+	Factor float64 `mapstructure:"factor"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Mode:   ModeReal,
+		Factor: 1,
+	}
+}
+
+// Clock returns the clock of the mode. The accelerated and the virtual
+// clocks start at the start time in RFC3339 or at the current time if it
+// is empty. The time of the virtual clock moves only when it is advanced.
+func (c *Config) Clock() (Clock, error) {
+	switch c.Mode {
+	case ModeReal, "":
+		return Real(), nil
+	case ModeAccelerated:
+		if c.Factor <= 0 {
+			return nil, fmt.Errorf("clock factor must be positive: %v", c.Factor)
+		}
+		start, err := c.start()
+		if err != nil {
+			return nil, err
+		}
+		return Accelerated(start, c.Factor), nil
+	case ModeVirtual:
+		start, err := c.start()
+		if err != nil {
+			return nil, err
+		}
+		return NewVirtual(start), nil
+	}
+	return nil, fmt.Errorf("unknown clock mode: %s", c.Mode)
+}
+
+func (c *Config) start() (time.Time, error) {
+	if c.Start == "" {
+		return time.Now(), nil
+	}
+	return time.Parse(time.RFC3339, c.Start)
+}
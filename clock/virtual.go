@@ -0,0 +1,149 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Virtual is the clock which time moves only by Advance or Step. It runs
+// a process step by step: every step fires the next timer.
+type Virtual struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*virtualTimer
+	added  chan struct{}
+}
+
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{
+		now:   start,
+		added: make(chan struct{}, 1),
+	}
+}
+
+func (c *Virtual) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now.UTC()
+}
+
+func (c *Virtual) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &virtualTimer{
+		clock:    c,
+		deadline: c.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+
+	c.timers = append(c.timers, t)
+
+	select {
+	case c.added <- struct{}{}:
+	default:
+	}
+
+	return t
+}
+
+func (c *Virtual) Sleep(d time.Duration) {
+	<-c.NewTimer(d).C()
+}
+
+// Advance moves the time forward by d and fires the timers expired on
+// the way in the order of their deadlines.
+func (c *Virtual) Advance(d time.Duration) {
+	end := c.Now().Add(d)
+	for {
+		c.mu.Lock()
+		t := c.first()
+		if t == nil || t.deadline.After(end) {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		c.fire(t)
+		c.mu.Unlock()
+	}
+}
+
+// Step moves the time to the deadline of the next timer and fires it.
+// It returns false if there are no timers.
+func (c *Virtual) Step() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := c.first()
+	if t == nil {
+		return c.now, false
+	}
+
+	c.fire(t)
+
+	return c.now, true
+}
+
+// Wait blocks until there are at least n timers waiting.
+func (c *Virtual) Wait(n int) {
+	for {
+		c.mu.Lock()
+		count := len(c.timers)
+		c.mu.Unlock()
+		if count >= n {
+			return
+		}
+		<-c.added
+	}
+}
+
+// first returns the timer with the earliest deadline.
+func (c *Virtual) first() *virtualTimer {
+	if len(c.timers) == 0 {
+		return nil
+	}
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	return c.timers[0]
+}
+
+func (c *Virtual) fire(t *virtualTimer) {
+	if t.deadline.After(c.now) {
+		c.now = t.deadline
+	}
+	c.remove(t)
+	t.ch <- c.now
+}
+
+func (c *Virtual) remove(t *virtualTimer) bool {
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type virtualTimer struct {
+	clock    *Virtual
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (t *virtualTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *virtualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
@@ -6,12 +6,23 @@ type Bet struct {
 	ID        uint64     `json:"id" db:"id"`
 	Value     uint       `json:"value" db:"value" validate:"required,gt=0"`
 	Winner    bool       `json:"winner" db:"winner"`
+	Settled   *uint      `json:"settled,omitempty" db:"settled"`
+	Auto      bool       `json:"auto" db:"auto"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 	LotID     uint       `json:"-" db:"lot_id"`
 	UserID    uint       `json:"user_id" db:"user_id" validate:"required"`
 }
 
+// Price returns the settled value of the bet if the bet is settled,
+// otherwise the submitted value.
+func (b *Bet) Price() uint {
+	if b.Settled != nil {
+		return *b.Settled
+	}
+	return b.Value
+}
+
 type BetService interface {
 	MinBet(lotID uint) (*Bet, error)
 	Bets(lotID uint) ([]*Bet, error)
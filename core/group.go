@@ -6,6 +6,7 @@ type Group struct {
 	Key        string            `json:"key" db:"group_key" validate:"required"`
 	Name       string            `json:"name" db:"name" validate:"required"`
 	ObjectType object.ObjectType `json:"object_type" db:"object_type" validate:"required,object_type"`
+	TimeZone   string            `json:"time_zone,omitempty" db:"time_zone" validate:"omitempty,time_zone"`
 }
 
 type ObjectTypeFilter interface {
@@ -18,6 +18,12 @@ const (
 	HistoryLotConfirmEdited      = "lot_confirm_edited"
 	HistoryLotDeleteConfirmation = "lot_delete_confirmation"
 	HistoryLotBetAccept          = "lot_bet_accept"
+	HistoryLotReplayed           = "lot_replayed"
+	HistoryLotReserved           = "lot_reserved"
+	HistoryReservationCanceled   = "reservation_canceled"
+	HistoryReservationExpired    = "reservation_expired"
+	HistoryLotPublished          = "lot_published"
+	HistoryLotExpired            = "lot_expired"
 	HistoryActChanged			 = "act_changed"
 	HistoryAllowChangedForAct    = "act_allow_changed=%d"
 )
@@ -29,6 +35,7 @@ type History struct {
 	RulePrice          *uint     `json:"rule_price,omitempty" db:"rule_price"`
 	CurrentPrice       *uint     `json:"current_price,omitempty" db:"current_price"`
 	CurrentPriceUserID *uint     `json:"current_price_user_id,omitempty" db:"current_price_user_id"`
+	Auto               bool      `json:"auto" db:"auto"`
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 	LotID              uint      `json:"lot_id" db:"lot_id"`
 	UserID             uint      `json:"user_id" db:"user_id"`
@@ -42,11 +49,19 @@ type HistoryService interface {
 	LotCompleted(userID uint, lot *Lot) error
 	LotNotWinner(userID uint, lot *Lot) error
 	BetPlaced(userID uint, lot *Lot) error
+	ProxyBetPlaced(userID uint, lot *Lot) error
 	BetCanceled(userID uint, lot *Lot) error
+	BookingCanceled(userID uint, lot *Lot) error
 	LotConfirmed(userID uint, lot *Lot) error
 	LotConfirmEdited(userID uint, lot *Lot) error
 	LotNoConfirm(userID uint, lot *Lot) error
-	LotDeleteConfirmation(userID uint, lot *Lot) error
+	LotDeleteConfirmation(userID, carrierID uint, lot *Lot) error
 	LotBetAccept(userID uint, lot *Lot) error
+	LotReplayed(userID uint, lot *Lot) error
+	LotReserved(userID uint, lot *Lot) error
+	ReservationCanceled(userID uint, lot *Lot) error
+	ReservationExpired(userID uint, lot *Lot) error
+	LotPublished(userID uint, lot *Lot) error
+	LotExpired(userID uint, lot *Lot) error
 	History(lotID uint) ([]*History, error)
 }
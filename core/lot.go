@@ -19,6 +19,8 @@ type RuleConfig struct {
 	Type     string          `json:"type" validate:"required,rule"`
 	Start    string          `json:"start" validate:"required"` // TODO: добавить валидацию значения, должно передаваться время
 	Duration Duration        `json:"duration" validate:"required"`
+	Date     string          `json:"date,omitempty"`     // дата запуска правила, 2006-01-02
+	Weekdays []string        `json:"weekdays,omitempty"` // дни недели запуска правила, mon..sun
 	Props    json.RawMessage `json:"props" validate:"required"`
 }
 
@@ -55,11 +57,38 @@ type Lot struct {
 	CurrentPrice uint            `json:"current_price,omitempty"`
 	UserPrice    uint            `json:"user_price,omitempty"`
 	Rule         string          `json:"rule,omitempty"`
+	Sealed       bool            `json:"sealed"`
 	RulePrice    uint            `json:"-"`
 	End          time.Time       `json:"end,omitempty"`
 	Rest         uint            `json:"rest"`
 	Bets         []*Bet          `json:"bets" groups:"manager"`
 	Urgent       bool            `json:"urgent" db:"urgent"`
+	ReservePrice *uint           `json:"reserve_price,omitempty" db:"reserve_price" validate:"omitempty,gt=0" groups:"manager"`
+	Fallback     uint            `json:"fallback" db:"fallback" groups:"manager"`
+	ReservedBy   *uint           `json:"reserved_by,omitempty" db:"reserved_by"`
+	ReservedTill *time.Time      `json:"reserved_till,omitempty" db:"reserved_till"`
+	PublishAt    *time.Time      `json:"publish_at,omitempty" db:"publish_at"`
+	ExpiresAt    *time.Time      `json:"expires_at,omitempty" db:"expires_at"`
+	TimeZone     string          `json:"time_zone,omitempty" db:"time_zone" validate:"omitempty,time_zone"`
+	GroupZone    *string         `json:"-" db:"group_time_zone"`
+}
+
+// Location returns the time zone of the lot rules: the time zone of the lot,
+// of its group or UTC.
+func (l *Lot) Location() *time.Location {
+	zones := []string{l.TimeZone}
+	if l.GroupZone != nil {
+		zones = append(zones, *l.GroupZone)
+	}
+	for _, zone := range zones {
+		if zone == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(zone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
 }
 
 func (l *Lot) AddBet(bet *Bet) {
@@ -108,6 +137,41 @@ func (l *Lot) UserBet(userID uint) *Bet {
 	return bet
 }
 
+// Reserved reports whether the price of the bet is within the hidden
+// reserve price of the lot. It is always true if there is no reserve.
+func (l *Lot) Reserved(bet *Bet) bool {
+	return l.ReservePrice == nil || bet.Price() <= *l.ReservePrice
+}
+
+// Published reports whether the lot is shown to carriers at the time.
+func (l *Lot) Published(t time.Time) bool {
+	return l.PublishAt == nil || !t.Before(*l.PublishAt)
+}
+
+// Expired reports whether the lot must be withdrawn at the time.
+func (l *Lot) Expired(t time.Time) bool {
+	return l.ExpiresAt != nil && !t.Before(*l.ExpiresAt)
+}
+
+// Holder returns the user holding the reservation of the lot at the time,
+// zero if the lot is not reserved or the reservation expired.
+func (l *Lot) Holder(t time.Time) uint {
+	if l.ReservedBy == nil || l.ReservedTill == nil || !t.Before(*l.ReservedTill) {
+		return 0
+	}
+	return *l.ReservedBy
+}
+
+func (l *Lot) Reserve(userID uint, till time.Time) {
+	l.ReservedBy = &userID
+	l.ReservedTill = &till
+}
+
+func (l *Lot) ClearReservation() {
+	l.ReservedBy = nil
+	l.ReservedTill = nil
+}
+
 func (l *Lot) ClearBets() {
 	l.Bets = []*Bet{}
 }
@@ -124,8 +188,8 @@ func (l *Lot) ClearBetsBefore(t time.Time) {
 
 func (l *Lot) UpdatePrice(userID uint) {
 	curBet := l.CurrentBet()
-	if curBet != nil {
-		l.CurrentPrice = curBet.Value
+	if curBet != nil && !l.Sealed {
+		l.CurrentPrice = curBet.Price()
 		l.Bet = curBet
 	}
 	userBet := l.UserBet(userID)
@@ -171,6 +235,15 @@ type LotService interface {
 	DeleteLot(lot *Lot) error
 }
 
+func ValidateTimeZone(validate *validator.Validate) {
+	validate.RegisterValidation("time_zone",
+		func(fl validator.FieldLevel) bool {
+			_, err := time.LoadLocation(fl.Field().String())
+			return err == nil
+		},
+	)
+}
+
 func ValidateState(validate *validator.Validate) {
 	validate.RegisterValidation("state",
 		func(fl validator.FieldLevel) bool {
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/json"
+	"gitlab/nefco/auction/core/object"
+	"time"
+)
+
+const (
+	LotChangeAdded   = "added"
+	LotChangeChanged = "changed"
+	LotChangeDeleted = "deleted"
+)
+
+// LotChange is the change of the lot made by the instance of the auction.
+// The other instances read the changes to notify their clients. The state
+// holds the fields of the lot kept by its process, which are not saved
+// with the lot.
+type LotChange struct {
+	ID         uint64          `json:"id" db:"id"`
+	Kind       string          `json:"kind" db:"kind"`
+	Instance   string          `json:"instance" db:"instance"`
+	State      object.JSONData `json:"state" db:"state"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	LotID      uint            `json:"lot_id" db:"lot_id"`
+	ReceiverID *uint           `json:"receiver_id" db:"receiver_id"`
+}
+
+type LotChangeState struct {
+	Rule         string     `json:"rule"`
+	BasePrice    uint       `json:"base_price"`
+	BetStep      uint       `json:"bet_step"`
+	CurrentPrice uint       `json:"current_price"`
+	RulePrice    uint       `json:"rule_price"`
+	Sealed       bool       `json:"sealed"`
+	BookedAt     *time.Time `json:"booked_at"`
+	End          time.Time  `json:"end"`
+	Rest         uint       `json:"rest"`
+	Bets         []*Bet     `json:"bets"`
+}
+
+func NewLotChange(kind, instance string, lot *Lot,
+	receiver *User) (*LotChange, error) {
+	state := &LotChangeState{
+		Rule:         lot.Rule,
+		BasePrice:    lot.BasePrice,
+		BetStep:      lot.BetStep,
+		CurrentPrice: lot.CurrentPrice,
+		RulePrice:    lot.RulePrice,
+		Sealed:       lot.Sealed,
+		BookedAt:     lot.BookedAt,
+		End:          lot.End,
+		Rest:         lot.Rest,
+		Bets:         lot.Bets,
+	}
+
+	// the bids of the sealed lot are not shared until they are revealed
+	if lot.Sealed {
+		state.CurrentPrice = 0
+		state.Bets = nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &LotChange{
+		Kind:     kind,
+		Instance: instance,
+		State:    data,
+		LotID:    lot.ID,
+	}
+
+	if receiver != nil {
+		change.ReceiverID = &receiver.ID
+	}
+
+	return change, nil
+}
+
+// Apply sets the state of the change to the lot read from the database.
+func (c *LotChange) Apply(lot *Lot) error {
+	state := &LotChangeState{}
+	if err := json.Unmarshal(c.State, state); err != nil {
+		return err
+	}
+
+	for _, bet := range state.Bets {
+		bet.LotID = lot.ID
+	}
+
+	lot.Rule = state.Rule
+	lot.BasePrice = state.BasePrice
+	lot.BetStep = state.BetStep
+	lot.CurrentPrice = state.CurrentPrice
+	lot.RulePrice = state.RulePrice
+	lot.Sealed = state.Sealed
+	lot.BookedAt = state.BookedAt
+	lot.End = state.End
+	lot.Rest = state.Rest
+	lot.Bets = state.Bets
+
+	// the sealed lot is shown at its base price
+	if state.Sealed {
+		lot.CurrentPrice = state.BasePrice
+		lot.Bet = nil
+		lot.Bets = []*Bet{}
+	}
+
+	return nil
+}
+
+// Receiver returns the user notified about the change, or nil if all users
+// are notified.
+func (c *LotChange) Receiver() *User {
+	if c.ReceiverID == nil {
+		return nil
+	}
+	return &User{ID: *c.ReceiverID}
+}
+
+type LotChangeService interface {
+	AppendLotChange(change *LotChange) error
+	LotChanges(afterID uint64, instance string) ([]*LotChange, error)
+	RemoteLotChanges(instance string) ([]*LotChange, error)
+	LastLotChange() (uint64, error)
+	DeleteLotChangesBefore(t time.Time) error
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"gitlab/nefco/auction/core/object"
+	"time"
+)
+
+const (
+	LotEventRuleStarted = "rule_started"
+)
+
+// LotEvent is a transition of the lot. The payload holds the full state of
+// the lot and its bets after the transition, so the lot is rebuilt by
+// applying its events in order of their versions.
+type LotEvent struct {
+	ID        uint64          `json:"id" db:"id"`
+	Type      string          `json:"type" db:"type"`
+	Version   uint            `json:"version" db:"version"`
+	Payload   object.JSONData `json:"payload" db:"payload"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	LotID     uint            `json:"lot_id" db:"lot_id"`
+	UserID    uint            `json:"user_id" db:"user_id"`
+}
+
+type LotEventPayload struct {
+	Rules        Rules           `json:"rules"`
+	Object       object.Object   `json:"object"`
+	BookedAt     *time.Time      `json:"booked_at"`
+	ManualBooked bool            `json:"manual_booked"`
+	ConfirmedAt  *time.Time      `json:"confirmed_at"`
+	CompletedAt  *time.Time      `json:"completed_at"`
+	DeletedAt    *time.Time      `json:"deleted_at"`
+	Confirm      object.JSONData `json:"confirm"`
+	Complete     object.JSONData `json:"complete"`
+	Urgent       bool            `json:"urgent"`
+	TimeZone     string          `json:"time_zone"`
+	ReservePrice *uint           `json:"reserve_price"`
+	Fallback     uint            `json:"fallback"`
+	ReservedBy   *uint           `json:"reserved_by"`
+	ReservedTill *time.Time      `json:"reserved_till"`
+	PublishAt    *time.Time      `json:"publish_at"`
+	ExpiresAt    *time.Time      `json:"expires_at"`
+	Bets         []*Bet          `json:"bets"`
+}
+
+func NewLotEvent(eventType string, userID uint, lot *Lot) (*LotEvent, error) {
+	payload := &LotEventPayload{
+		Rules:        lot.Rules,
+		Object:       lot.Object,
+		BookedAt:     lot.BookedAt,
+		ManualBooked: lot.ManualBooked,
+		ConfirmedAt:  lot.ConfirmedAt,
+		CompletedAt:  lot.CompletedAt,
+		DeletedAt:    lot.DeletedAt,
+		Confirm:      lot.Confirm,
+		Complete:     lot.Complete,
+		Urgent:       lot.Urgent,
+		TimeZone:     lot.TimeZone,
+		ReservePrice: lot.ReservePrice,
+		Fallback:     lot.Fallback,
+		ReservedBy:   lot.ReservedBy,
+		ReservedTill: lot.ReservedTill,
+		PublishAt:    lot.PublishAt,
+		ExpiresAt:    lot.ExpiresAt,
+		Bets:         lot.Bets,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &LotEvent{
+		Type:    eventType,
+		Payload: data,
+		LotID:   lot.ID,
+		UserID:  userID,
+	}
+
+	return event, nil
+}
+
+type LotEventService interface {
+	LotEvents(lotID uint) ([]*LotEvent, error)
+	AppendLotEvent(eventType string, userID uint, lot *Lot) error
+}
+
+// Apply sets the state of the lot from the event.
+func (l *Lot) Apply(event *LotEvent) error {
+	if event.LotID != l.ID {
+		return fmt.Errorf("lot event %d of other lot %d", event.ID, event.LotID)
+	}
+
+	payload := &LotEventPayload{}
+
+	if err := json.Unmarshal(event.Payload, payload); err != nil {
+		return fmt.Errorf("lot event %d unmarshal error: %s", event.ID, err)
+	}
+
+	l.Rules = payload.Rules
+	l.Object = payload.Object
+	l.BookedAt = payload.BookedAt
+	l.ManualBooked = payload.ManualBooked
+	l.ConfirmedAt = payload.ConfirmedAt
+	l.CompletedAt = payload.CompletedAt
+	l.DeletedAt = payload.DeletedAt
+	l.Confirm = payload.Confirm
+	l.Complete = payload.Complete
+	l.Urgent = payload.Urgent
+	l.TimeZone = payload.TimeZone
+	l.ReservePrice = payload.ReservePrice
+	l.Fallback = payload.Fallback
+	l.ReservedBy = payload.ReservedBy
+	l.ReservedTill = payload.ReservedTill
+	l.PublishAt = payload.PublishAt
+	l.ExpiresAt = payload.ExpiresAt
+	l.Bets = payload.Bets
+	l.Bet = nil
+
+	for _, bet := range l.Bets {
+		bet.LotID = l.ID
+	}
+
+	return nil
+}
+
+// ProjectLot rebuilds the lot from its events up to the version, all
+// events are applied if the version is zero. The events must be ordered
+// by version without gaps.
+func ProjectLot(lot *Lot, events []*LotEvent, version uint) error {
+	for i, event := range events {
+		if version > 0 && event.Version > version {
+			break
+		}
+
+		if event.Version != uint(i+1) {
+			return fmt.Errorf("lot event version %d expected, got %d",
+				i+1, event.Version)
+		}
+
+		if err := lot.Apply(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
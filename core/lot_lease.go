@@ -0,0 +1,40 @@
+package core
+
+import (
+	"gitlab/nefco/auction/errors"
+	"net/http"
+	"time"
+)
+
+var (
+	LotLeaseNotFound = errors.NotFound("Lot lease not found")
+	LotLeaseLost     = errors.NewError("Lot lease lost", http.StatusServiceUnavailable)
+)
+
+// LotLease is the ownership of the lot process by the instance of the
+// auction. Only the instance holding the lease runs the process of the
+// lot, the lease is renewed while the process runs and is taken over by
+// another instance when it expires.
+type LotLease struct {
+	LotID     uint      `json:"lot_id" db:"lot_id"`
+	Instance  string    `json:"instance" db:"instance"`
+	Address   string    `json:"address" db:"address"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Expired reports whether the lease is expired at the time t.
+func (l *LotLease) Expired(t time.Time) bool {
+	return !l.ExpiresAt.After(t)
+}
+
+type LotLeaseService interface {
+	LotLease(lotID uint) (*LotLease, error)
+	AcquireLotLease(lease *LotLease) (*LotLease, error)
+	RenewLotLease(lease *LotLease) (bool, error)
+	ReleaseLotLease(lease *LotLease) error
+	DeleteLotLease(lotID uint) error
+	UnleasedLots() ([]uint, error)
+	RemoteLots(instance string) ([]uint, error)
+	DeleteExpiredLotLeases() error
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"gitlab/nefco/auction/db"
 	"net/url"
+	"time"
 
 	validator "gopkg.in/go-playground/validator.v9"
 )
@@ -26,6 +27,18 @@ type ObjectData interface {
 	CheckFilter(f ObjectFilter) bool
 }
 
+// ObjectDates is implemented by the object data with dates, which are moved
+// forward when the lot is created from a template.
+type ObjectDates interface {
+	MoveDates(days int)
+}
+
+// ObjectDeadline is implemented by the object data with the time after
+// which its lot can't be booked, it is the default expiry of the lot.
+type ObjectDeadline interface {
+	Deadline() (time.Time, bool)
+}
+
 type ObjectAction interface {
 	ObjectData() ObjectData
 	ObjectFilter() ObjectFilter
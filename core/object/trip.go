@@ -183,6 +183,26 @@ func (t Trip) CheckFilter(of ObjectFilter) bool {
 	return true
 }
 
+func (t *Trip) MoveDates(days int) {
+	for _, point := range t.Points {
+		point.Date = point.Date.AddDate(0, 0, days)
+	}
+}
+
+// Deadline returns the date of the first loading point of the trip.
+func (t *Trip) Deadline() (time.Time, bool) {
+	var deadline time.Time
+	for _, point := range t.Points {
+		if point.Unloading {
+			continue
+		}
+		if deadline.IsZero() || point.Date.Before(deadline) {
+			deadline = point.Date
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
+
 type TripAction struct{}
 
 func (TripAction) ObjectData() ObjectData {
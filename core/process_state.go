@@ -0,0 +1,39 @@
+package core
+
+import (
+	"gitlab/nefco/auction/core/object"
+	"gitlab/nefco/auction/errors"
+	"time"
+)
+
+var (
+	ProcessStateNotFound = errors.NotFound("Process state not found")
+)
+
+// ProcessState is the state of the lot process saved to restore the
+// process exactly after restart.
+type ProcessState struct {
+	LotID     uint            `json:"lot_id" db:"lot_id"`
+	Rule      string          `json:"rule" db:"rule"`
+	RuleIndex int             `json:"rule_index" db:"rule_index"`
+	StartAt   time.Time       `json:"start_at" db:"start_at"`
+	EndAt     time.Time       `json:"end_at" db:"end_at"`
+	Added     time.Duration   `json:"added" db:"added"`
+	State     object.JSONData `json:"state" db:"state"`
+	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// ProcessesInfo is the summary of the running processes, the processes are
+// counted by their current rule.
+type ProcessesInfo struct {
+	Count     int             `json:"count"`
+	Rules     map[string]int  `json:"rules"`
+	Processes []*ProcessState `json:"processes"`
+}
+
+type ProcessStateService interface {
+	ProcessStates() ([]*ProcessState, error)
+	ProcessState(lotID uint) (*ProcessState, error)
+	SaveProcessState(state *ProcessState) error
+	DeleteProcessState(lotID uint) error
+}
@@ -0,0 +1,21 @@
+package core
+
+import "time"
+
+// ProxyBet is a floor price up to which the server places bets for
+// the user automatically.
+type ProxyBet struct {
+	ID        uint64     `json:"id" db:"id"`
+	Floor     uint       `json:"floor" db:"floor" validate:"required,gt=0"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	LotID     uint       `json:"-" db:"lot_id"`
+	UserID    uint       `json:"user_id" db:"user_id"`
+}
+
+type ProxyBetService interface {
+	ProxyBets(lotID uint) ([]*ProxyBet, error)
+	UserProxyBet(lotID uint, userID uint) (*ProxyBet, error)
+	CreateProxyBet(proxyBet *ProxyBet) error
+	DeleteProxyBet(proxyBet *ProxyBet) error
+}
@@ -0,0 +1,28 @@
+package core
+
+import "time"
+
+// RuleOccurrence is the occurrence of the rule during the day of the
+// preview. The index is the index of the rule config, it is -1 for the
+// wait rules inserted between the rules.
+type RuleOccurrence struct {
+	Index  int         `json:"index"`
+	Rule   string      `json:"rule"`
+	Start  time.Time   `json:"start"`
+	End    time.Time   `json:"end"`
+	Config interface{} `json:"config,omitempty"`
+}
+
+// RuleError is the error of the rule config. The fields are the failed
+// fields of the config with their validation tags.
+type RuleError struct {
+	Index  int      `json:"index"`
+	Error  string   `json:"error"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+type RulePreview struct {
+	Date     string            `json:"date"`
+	Timeline []*RuleOccurrence `json:"timeline"`
+	Errors   []*RuleError      `json:"errors,omitempty"`
+}
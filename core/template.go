@@ -0,0 +1,122 @@
+package core
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"gitlab/nefco/auction/core/object"
+	"gitlab/nefco/auction/db"
+	"gitlab/nefco/auction/errors"
+	"time"
+)
+
+const (
+	RecurrenceDaily    = "daily"
+	RecurrenceWeekdays = "weekdays"
+	RecurrenceCron     = "cron"
+)
+
+var (
+	TemplateNotFound = errors.NotFound("Template not found")
+)
+
+// Recurrence is the time when the lots are created from the template.
+type Recurrence struct {
+	Type     string   `json:"type" validate:"required"`
+	Time     string   `json:"time,omitempty"`     // время создания лота для daily и weekdays, 15:04
+	Weekdays []string `json:"weekdays,omitempty"` // дни недели для weekdays, mon..sun
+	Cron     string   `json:"cron,omitempty"`     // минуты часы дни месяцы дни_недели для cron
+}
+
+func (r Recurrence) Value() (driver.Value, error) {
+	return db.JSONValue(&r)
+}
+
+func (r *Recurrence) Scan(src interface{}) error {
+	return db.JSONScan(src, r)
+}
+
+// LotSkeleton is the part of the lot repeated by the template.
+type LotSkeleton struct {
+	Rules        Rules         `json:"rules" validate:"required,min=1,dive,required"`
+	GroupKey     string        `json:"group_key" validate:"required"`
+	Object       object.Object `json:"object" validate:"required"`
+	Urgent       bool          `json:"urgent"`
+	ReservePrice *uint         `json:"reserve_price,omitempty" validate:"omitempty,gt=0"`
+	Fallback     uint          `json:"fallback"`
+	TimeZone     string        `json:"time_zone,omitempty" validate:"omitempty,time_zone"`
+	PublishAt    *time.Time    `json:"publish_at,omitempty"`
+	ExpiresAt    *time.Time    `json:"expires_at,omitempty"`
+}
+
+func (s LotSkeleton) Value() (driver.Value, error) {
+	return db.JSONValue(&s)
+}
+
+func (s *LotSkeleton) Scan(src interface{}) error {
+	return db.JSONScan(src, s)
+}
+
+type LotTemplate struct {
+	ID           uint        `json:"id" db:"id"`
+	Name         string      `json:"name" db:"name" validate:"required"`
+	Lot          LotSkeleton `json:"lot" db:"lot"`
+	Recurrence   Recurrence  `json:"recurrence" db:"recurrence"`
+	StartAt      time.Time   `json:"start_at" db:"start_at" validate:"required"`
+	NextObjectID uint        `json:"next_object_id" db:"next_object_id" validate:"required"`
+	LastRunAt    *time.Time  `json:"last_run_at,omitempty" db:"last_run_at"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
+	DeletedAt    *time.Time  `json:"-" db:"deleted_at"`
+	UserID       uint        `json:"-" db:"user_id"`
+}
+
+// NewLot returns the lot of the template with the object id of the next
+// lot. The dates of the lot and of its object are moved forward by days,
+// the number of days passed since the start of the template.
+func (t *LotTemplate) NewLot(days int) (*Lot, error) {
+	data, err := json.Marshal(&t.Lot)
+	if err != nil {
+		return nil, err
+	}
+
+	skeleton := &LotSkeleton{}
+
+	if err := json.Unmarshal(data, skeleton); err != nil {
+		return nil, err
+	}
+
+	if d, ok := skeleton.Object.Data.(object.ObjectDates); ok {
+		d.MoveDates(days)
+	}
+
+	lot := &Lot{
+		Rules:        skeleton.Rules,
+		GroupKey:     skeleton.GroupKey,
+		ObjectID:     t.NextObjectID,
+		Object:       skeleton.Object,
+		Urgent:       skeleton.Urgent,
+		ReservePrice: skeleton.ReservePrice,
+		Fallback:     skeleton.Fallback,
+		TimeZone:     skeleton.TimeZone,
+		PublishAt:    moveDate(skeleton.PublishAt, days),
+		ExpiresAt:    moveDate(skeleton.ExpiresAt, days),
+	}
+
+	return lot, nil
+}
+
+func moveDate(t *time.Time, days int) *time.Time {
+	if t == nil {
+		return nil
+	}
+	d := t.AddDate(0, 0, days)
+	return &d
+}
+
+type TemplateService interface {
+	Templates() ([]*LotTemplate, error)
+	Template(id uint) (*LotTemplate, error)
+	CreateTemplate(template *LotTemplate) error
+	SaveTemplate(template *LotTemplate) error
+	DeleteTemplate(template *LotTemplate) error
+}
@@ -4,6 +4,7 @@ import (
 	"gitlab/nefco/auction/core/object"
 	"gitlab/nefco/auction/errors"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -30,14 +31,16 @@ type UserInfo struct {
 
 type User struct {
 	UserInfo
-	ID         uint              `json:"id" db:"id"`
-	Username   string            `json:"username" db:"username" validate:"required"`
-	Password   string            `json:"password,omitempty" db:"password" validate:"required"`
-	Blocked    bool              `json:"blocked" db:"blocked"`
-	ObjectType object.ObjectType `json:"object_type" db:"object_type" validate:"required,object_type"`
-	BackKey    string            `json:"back_key,omitempty" db:"back_key" groups:"root"`
-	Groups     []*Group          `json:"groups,omitempty"`
-	lotsFilter LotsFilter
+	ID           uint              `json:"id" db:"id"`
+	Username     string            `json:"username" db:"username" validate:"required"`
+	Password     string            `json:"password,omitempty" db:"password" validate:"required"`
+	Blocked      bool              `json:"blocked" db:"blocked"`
+	BlockedUntil *time.Time        `json:"blocked_until,omitempty" db:"blocked_until"`
+	Score        *uint             `json:"score,omitempty" groups:"manager,root"`
+	ObjectType   object.ObjectType `json:"object_type" db:"object_type" validate:"required,object_type"`
+	BackKey      string            `json:"back_key,omitempty" db:"back_key" groups:"root"`
+	Groups       []*Group          `json:"groups,omitempty"`
+	lotsFilter   LotsFilter
 }
 
 func (u *User) Level() string {
@@ -50,9 +53,21 @@ func (u *User) Level() string {
 	return LevelUser
 }
 
+// IsBlocked reports whether the user can't place bets at the time.
+func (u *User) IsBlocked(t time.Time) bool {
+	if !u.Blocked {
+		return false
+	}
+	return u.BlockedUntil == nil || t.Before(*u.BlockedUntil)
+}
+
 func (u *User) Check(lot *Lot) bool {
+	return u.CheckGroup(lot.GroupKey)
+}
+
+func (u *User) CheckGroup(groupKey string) bool {
 	for _, group := range u.Groups {
-		if group.Key == lot.GroupKey {
+		if group.Key == groupKey {
 			return true
 		}
 	}
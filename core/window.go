@@ -7,6 +7,7 @@ type Window struct {
 }
 
 type LoadDate struct {
-	Date    string   `json:"date"`
-	Windows []Window `json:"windows"`
+	Date     string   `json:"date"`
+	Windows  []Window `json:"windows"`
+	TimeZone string   `json:"time_zone,omitempty"`
 }
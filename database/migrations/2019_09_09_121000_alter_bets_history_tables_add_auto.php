@@ -0,0 +1,40 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterBetsHistoryTablesAddAuto extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('bets', function (Blueprint $table) {
+            $table->boolean('auto')->default(false);
+        });
+
+        Schema::table('history', function (Blueprint $table) {
+            $table->boolean('auto')->default(false);
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('bets', function (Blueprint $table) {
+            $table->dropColumn('auto');
+        });
+
+        Schema::table('history', function (Blueprint $table) {
+            $table->dropColumn('auto');
+        });
+    }
+}
@@ -0,0 +1,40 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterLotsGroupsTablesAddTimeZone extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('lots', function (Blueprint $table) {
+            $table->string('time_zone', 64)->default('');
+        });
+
+        Schema::table('groups', function (Blueprint $table) {
+            $table->string('time_zone', 64)->default('');
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('lots', function (Blueprint $table) {
+            $table->dropColumn('time_zone');
+        });
+
+        Schema::table('groups', function (Blueprint $table) {
+            $table->dropColumn('time_zone');
+        });
+    }
+}
@@ -0,0 +1,41 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateProcessesTable extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('processes', function (Blueprint $table) {
+            $table->unsignedInteger('lot_id');
+            $table->string('rule');
+            $table->integer('rule_index');
+            $table->dateTime('start_at');
+            $table->dateTime('end_at');
+            $table->bigInteger('added');
+            $table->text('state')->nullable();
+            $table->dateTime('updated_at');
+
+            $table->primary('lot_id');
+
+            $table->foreign('lot_id')->references('id')->on('lots');
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('processes');
+    }
+}
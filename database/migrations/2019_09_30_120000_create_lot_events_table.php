@@ -0,0 +1,42 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateLotEventsTable extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('lot_events', function (Blueprint $table) {
+            $table->bigIncrements('id');
+            $table->string('type');
+            $table->unsignedInteger('version');
+            $table->text('payload');
+            $table->dateTime('created_at');
+            $table->unsignedInteger('lot_id');
+            $table->unsignedInteger('user_id');
+
+            $table->unique(['lot_id', 'version']);
+            $table->index('user_id');
+
+            $table->foreign('lot_id')->references('id')->on('lots');
+            $table->foreign('user_id')->references('id')->on('users');
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('lot_events');
+    }
+}
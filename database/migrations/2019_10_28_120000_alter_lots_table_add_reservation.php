@@ -0,0 +1,36 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class AlterLotsTableAddReservation extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::table('lots', function (Blueprint $table) {
+            $table->unsignedInteger('reserved_by')->nullable();
+            $table->dateTime('reserved_till')->nullable();
+
+            $table->foreign('reserved_by')->references('id')->on('users');
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::table('lots', function (Blueprint $table) {
+            $table->dropForeign(['reserved_by']);
+            $table->dropColumn(['reserved_by', 'reserved_till']);
+        });
+    }
+}
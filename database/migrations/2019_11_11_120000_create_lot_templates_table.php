@@ -0,0 +1,44 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateLotTemplatesTable extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('lot_templates', function (Blueprint $table) {
+            $table->increments('id');
+            $table->string('name');
+            $table->text('lot');
+            $table->text('recurrence');
+            $table->dateTime('start_at');
+            $table->unsignedInteger('next_object_id');
+            $table->dateTime('last_run_at')->nullable();
+            $table->dateTime('created_at');
+            $table->dateTime('updated_at');
+            $table->dateTime('deleted_at')->nullable();
+            $table->unsignedInteger('user_id');
+
+            $table->index('user_id');
+
+            $table->foreign('user_id')->references('id')->on('users');
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('lot_templates');
+    }
+}
@@ -0,0 +1,39 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateLotLeasesTable extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('lot_leases', function (Blueprint $table) {
+            $table->unsignedInteger('lot_id');
+            $table->string('instance');
+            $table->string('address');
+            $table->dateTime('expires_at');
+            $table->dateTime('updated_at');
+
+            $table->primary('lot_id');
+            $table->index('instance');
+
+            $table->foreign('lot_id')->references('id')->on('lots');
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('lot_leases');
+    }
+}
@@ -0,0 +1,39 @@
+<?php
+
+use Illuminate\Support\Facades\Schema;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Database\Migrations\Migration;
+
+class CreateLotChangesTable extends Migration
+{
+    /**
+     * Run the migrations.
+     *
+     * @return void
+     */
+    public function up()
+    {
+        Schema::create('lot_changes', function (Blueprint $table) {
+            $table->bigIncrements('id');
+            $table->string('kind');
+            $table->string('instance');
+            $table->text('state');
+            $table->dateTime('created_at');
+            $table->unsignedInteger('lot_id');
+            $table->unsignedInteger('receiver_id')->nullable();
+
+            $table->index('created_at');
+            $table->index(['lot_id', 'id']);
+        });
+    }
+
+    /**
+     * Reverse the migrations.
+     *
+     * @return void
+     */
+    public function down()
+    {
+        Schema::dropIfExists('lot_changes');
+    }
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"gitlab/nefco/auction/clock"
+	"time"
 
 	_ "github.com/denisenkom/go-mssqldb"
 	_ "github.com/go-sql-driver/mysql"
@@ -15,9 +17,12 @@ import (
 
 type DB struct {
 	*sqlx.DB
+	clock clock.Clock
 }
 
-func New(cfg *Config) (*DB, error) {
+// New connects to the database. The clock is used by the services for the
+// times they save.
+func New(cfg *Config, clk clock.Clock) (*DB, error) {
 	var dsn string
 
 	switch cfg.Driver {
@@ -46,7 +51,7 @@ func New(cfg *Config) (*DB, error) {
 		return nil, err
 	}
 
-	return &DB{db}, nil
+	return &DB{db, clk}, nil
 }
 
 func (db *DB) Begin() (*Tx, error) {
@@ -54,11 +59,35 @@ func (db *DB) Begin() (*Tx, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Tx{tx}, nil
+	return &Tx{tx: tx, clock: db.clock}, nil
+}
+
+func (db *DB) Clock() clock.Clock {
+	return db.clock
 }
 
 type Tx struct {
-	tx *sqlx.Tx
+	tx         *sqlx.Tx
+	clock      clock.Clock
+	committed  []func()
+	rolledBack []func()
+}
+
+// AfterCommit adds the function which is called after the transaction is
+// committed. It is not called if the transaction is rolled back.
+func (tx *Tx) AfterCommit(f func()) {
+	tx.committed = append(tx.committed, f)
+}
+
+// AfterRollback adds the function which is called after the transaction is
+// rolled back or its commit fails.
+func (tx *Tx) AfterRollback(f func()) {
+	tx.rolledBack = append(tx.rolledBack, f)
+}
+
+// Now returns the current time of the clock of the database.
+func (tx *Tx) Now() time.Time {
+	return tx.clock.Now()
 }
 
 func (tx *Tx) prepareQuery(query string, arg interface{}) (string, []interface{}, error) {
@@ -75,13 +104,28 @@ func (tx *Tx) prepareQuery(query string, arg interface{}) (string, []interface{}
 }
 
 func (tx *Tx) Commit() error {
-	return tx.tx.Commit()
+	if err := tx.tx.Commit(); err != nil {
+		tx.end(tx.rolledBack)
+		return err
+	}
+	tx.end(tx.committed)
+	return nil
 }
 
 func (tx *Tx) Rollback() error {
+	defer tx.end(tx.rolledBack)
 	return tx.tx.Rollback()
 }
 
+// end calls the functions added for the end of the transaction, each of
+// them is called once.
+func (tx *Tx) end(ff []func()) {
+	tx.committed, tx.rolledBack = nil, nil
+	for _, f := range ff {
+		f()
+	}
+}
+
 func (tx *Tx) Exec(query string, arg interface{}) (sql.Result, error) {
 	query, args, err := sqlx.Named(query, arg)
 	if err != nil {
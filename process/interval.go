@@ -2,6 +2,7 @@ package process
 
 import (
 	"errors"
+	"gitlab/nefco/auction/clock"
 	"time"
 )
 
@@ -11,45 +12,64 @@ type Interval interface {
 	Duration() time.Duration
 	Rest(t time.Time) time.Duration
 	Contains(t time.Time) bool
+	Next(t time.Time) time.Time
+	Clock() clock.Clock
 }
 
+// Daily is implemented by the intervals repeated every day.
+type Daily interface {
+	Interval
+	Offset() time.Duration
+}
+
+// interval is repeated every day. The start and the end of the interval
+// are wall clock times in its location, so the interval follows DST
+// changes and may span midnight.
 type interval struct {
-	startTime time.Time
-	duration  time.Duration
+	offset   time.Duration
+	duration time.Duration
+	loc      *time.Location
+	clock    clock.Clock
 }
 
-func NewInterval(start time.Time, duration time.Duration) (*interval, error) {
+func NewInterval(offset time.Duration, duration time.Duration,
+	loc *time.Location, clk clock.Clock) (*interval, error) {
 	if duration/time.Hour > 24 {
 		err := errors.New(`the duration of the rule 
 			should not be more than 24 hours`)
 		return nil, err
 	}
-	if start.After(start.Add(duration)) {
+	if duration < 0 {
 		err := errors.New(`the beginning of the period should be 
 			earlier than the end of the period`)
 		return nil, err
 	}
 	i := &interval{
-		startTime: start,
-		duration:  duration,
+		offset:   offset,
+		duration: duration,
+		loc:      loc,
+		clock:    clk,
 	}
 	return i, nil
 }
 
-func ParseInterval(startTime string, duration time.Duration /* endTime string */) (*interval, error) {
+func ParseInterval(startTime string, duration time.Duration,
+	loc *time.Location, clk clock.Clock) (*interval, error) {
 	start, err := time.Parse("15:04:05", startTime)
 	if err != nil {
 		return nil, err
 	}
-	return NewInterval(start, duration)
+	return NewInterval(timeOfDay(start), duration, loc, clk)
 }
 
+// Start returns the start of the current occurrence or the last one if
+// it has already ended.
 func (i interval) Start() time.Time {
-	return date(i.startTime)
+	return i.occurrence(i.clock.Now().Add(lookahead))
 }
 
 func (i interval) End() time.Time {
-	return i.Start().Add(i.duration)
+	return i.end(i.Start())
 }
 
 func (i interval) Duration() time.Duration {
@@ -61,21 +81,104 @@ func (i interval) Rest(t time.Time) time.Duration {
 }
 
 func (i interval) Contains(t time.Time) bool {
-	return t.After(i.Start()) && t.Before(i.End())
+	start := i.occurrence(t)
+	return t.After(start) && t.Before(i.end(start))
 }
 
-func date(t time.Time) time.Time {
-	year, month, day := now().Date()
+// Next returns the start of the first occurrence of the interval after t.
+func (i interval) Next(t time.Time) time.Time {
+	start := i.occurrence(t)
+	if !start.After(t) {
+		start = wallclock(start.In(i.loc).AddDate(0, 0, 1), i.offset, i.loc)
+	}
+	return start
+}
 
-	hour, min, sec := t.Clock()
+func (i interval) Clock() clock.Clock {
+	return i.clock
+}
+
+// Offset returns the wall clock time of the start of the interval.
+func (i interval) Offset() time.Duration {
+	return i.offset
+}
+
+// occurrence returns the start of the last occurrence started before t.
+func (i interval) occurrence(t time.Time) time.Time {
+	start := wallclock(t.In(i.loc), i.offset, i.loc)
+	if start.After(t) {
+		start = wallclock(t.In(i.loc).AddDate(0, 0, -1), i.offset, i.loc)
+	}
+	return start
+}
+
+func (i interval) end(start time.Time) time.Time {
+	return wallclock(start.In(i.loc), i.offset+i.duration, i.loc)
+}
+
+// period is an interval with a fixed start date, it does not repeat.
+type period struct {
+	startTime time.Time
+	duration  time.Duration
+	clock     clock.Clock
+}
+
+func NewPeriod(start time.Time, duration time.Duration,
+	clk clock.Clock) (*period, error) {
+	if duration < 0 {
+		err := errors.New(`the beginning of the period should be 
+			earlier than the end of the period`)
+		return nil, err
+	}
+	p := &period{
+		startTime: start,
+		duration:  duration,
+		clock:     clk,
+	}
+	return p, nil
+}
+
+func (p period) Start() time.Time {
+	return p.startTime
+}
+
+func (p period) End() time.Time {
+	return p.startTime.Add(p.duration)
+}
+
+func (p period) Duration() time.Duration {
+	return p.duration
+}
 
-	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+func (p period) Rest(t time.Time) time.Duration {
+	return p.End().Sub(t)
 }
 
-func now() time.Time {
-	return time.Now().UTC()
+func (p period) Contains(t time.Time) bool {
+	return t.After(p.Start()) && t.Before(p.End())
 }
 
-func Now() time.Time {
-	return now()
+func (p period) Clock() clock.Clock {
+	return p.clock
+}
+
+func (p period) Next(t time.Time) time.Time {
+	if p.startTime.After(t) {
+		return p.startTime
+	}
+	return time.Time{}
+}
+
+// wallclock returns the time of the day of d in the location loc with
+// the wall clock offset from midnight.
+func wallclock(d time.Time, offset time.Duration, loc *time.Location) time.Time {
+	year, month, day := d.Date()
+	return time.Date(year, month, day, 0, 0, int(offset/time.Second), 0, loc).UTC()
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	hour, min, sec := t.Clock()
+	return time.Duration(hour)*time.Hour +
+		time.Duration(min)*time.Minute +
+		time.Duration(sec)*time.Second
 }
@@ -1,11 +1,14 @@
 package process
 
 import (
+	"context"
+	"encoding/json"
+	"gitlab/nefco/auction/clock"
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/core/object"
-	"gitlab/nefco/auction/db"
 	"gitlab/nefco/auction/errors"
 	"gitlab/nefco/auction/service"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -17,6 +20,9 @@ var (
 	lotAlreadyConfirmed = errors.BadRequest("Lot already confirmed")
 	lotAlreadyCompleted = errors.BadRequest("Lot already completed")
 	confirmInfoInvalid  = errors.BadRequest("Confirm info invalid")
+	proxyBetDisabled    = errors.BadRequest("Proxy bet disabled")
+	processStopped      = errors.BadRequest("Process stopped")
+	RuleNotActive       = errors.New("Rule not active")
 )
 
 type Action interface {
@@ -31,6 +37,11 @@ type PlaceBet interface {
 	Value() uint
 }
 
+type PlaceProxy interface {
+	Action
+	Floor() uint
+}
+
 type ConfirmLot interface {
 	Action
 	Info() object.JSONData
@@ -52,33 +63,39 @@ type RuleProxy interface {
 	BackService
 	core.LotService
 	core.BetService
+	core.ProxyBetService
 	core.HistoryService
+	Now() time.Time
 	End() time.Time
 	ProcessLot() *core.Lot
 	Run(rule Rule) error
 	Next() error
 	Complete() error
 	Prolong(d time.Duration)
+	Refresh(rule Rule) error
+	Done() <-chan struct{}
 }
 
 type ruleProxy struct {
 	BackService
 	core.LotService
 	core.BetService
+	core.ProxyBetService
 	core.HistoryService
 	*process
 	lot *core.Lot
 }
 
-func newRuleProxy(prc *process, tx *db.Tx,
+func newRuleProxy(prc *process, tx Tx,
 	executor *core.User, lot *core.Lot) RuleProxy {
 	return &ruleProxy{
-		BackService:    service.NewBackService(prc.cfgBackService, executor, lot),
-		LotService:     service.NewLotService(tx),
-		BetService:     service.NewBetService(tx),
-		HistoryService: service.NewHistoryService(tx),
-		process:        prc,
-		lot:            lot,
+		BackService:     prc.store.BackService(executor, lot),
+		LotService:      tx,
+		BetService:      tx,
+		ProxyBetService: tx,
+		HistoryService:  tx,
+		process:         prc,
+		lot:             lot,
 	}
 }
 
@@ -99,14 +116,35 @@ type Rule interface {
 	Sync(lot *core.Lot)
 }
 
+// ProxyRule is implemented by rules which place bets automatically on
+// behalf of users with a proxy bet.
+type ProxyRule interface {
+	ProxyBet(prx RuleProxy) ([]*core.Bet, error)
+}
+
+// StateRule is implemented by rules with the state which is saved with
+// the process state to restore the rule after restart.
+type StateRule interface {
+	State() interface{}
+}
+
 type Process interface {
 	LotID() uint
 	Stop() error
 	PlaceBet(act PlaceBet) error
 	CancelBet(act Action) error
+	PlaceProxy(act PlaceProxy) error
+	CancelProxy(act Action) error
 	ConfirmLot(act ConfirmLot) error
 	AcceptBet(act AcceptBet) error
+	PlaceReservation(act Action, d time.Duration) error
+	CancelReservation(act Action) error
+	CheckReschedule(rules []Rule, lot *core.Lot) error
+	Reschedule(rules []Rule) error
 	Sync(lot *core.Lot)
+	Rule() string
+	State() (*core.ProcessState, error)
+	Shutdown(ctx context.Context) error
 }
 
 type ProcessService interface {
@@ -115,40 +153,64 @@ type ProcessService interface {
 	LotChangedWithReceiver(lot *core.Lot, receiver *core.User)
 }
 
+// process is the actor of the lot. The commands of users, the ends of the
+// timers and the refreshes of rules are sent to its mailbox and handled one
+// by one in its goroutine, which owns the rules, the timeline and the
+// reservation. The current and the next rule are also read by Sync from
+// other goroutines, so they are changed under the mutex. The time of the
+// process, its rules and its timers is the time of its clock.
 type process struct {
-	lotID          uint
-	rules          []Rule
-	db             *db.DB
-	prcSvc         ProcessService
-	cfgBackService *service.ConfigBackService
-	timeline       Timeline
-	currentRule    Rule
-	nextRule       Rule
-	runner         *core.User
-	logger         *zap.Logger
+	lotID       uint
+	rules       []Rule
+	store       Store
+	prcSvc      ProcessService
+	clock       clock.Clock
+	timeline    Timeline
+	mu          sync.RWMutex
+	currentRule Rule
+	nextRule    Rule
+	added       time.Duration
+	completed   bool
+	reservation chan bool
+	runner      *core.User
+	mailbox     chan func()
+	pending     []func()
+	changed     *core.Lot
+	done        chan struct{}
+	stopped     chan struct{}
+	stopOnce    sync.Once
+	logger      *zap.Logger
 }
 
 func New(
 	executor *core.User, lot *core.Lot, rules []Rule,
-	database *db.DB, prcSvc ProcessService,
-	cfgBackService *service.ConfigBackService,
-	tx *db.Tx, startRule Rule,
+	store Store, prcSvc ProcessService, clk clock.Clock,
+	tx Tx, startRule Rule, added time.Duration,
 ) (*process, error) {
 	p := &process{
-		lotID:          lot.ID,
-		rules:          rules,
-		db:             database,
-		prcSvc:         prcSvc,
-		cfgBackService: cfgBackService,
-		timeline:       newTimeline(),
-		runner:         executor,
-		logger:         zap.L().Named("process").With(zap.Uint("lot_id", lot.ID)),
+		lotID:   lot.ID,
+		rules:   rules,
+		store:   store,
+		prcSvc:  prcSvc,
+		clock:   clk,
+		added:   added,
+		runner:  executor,
+		mailbox: make(chan func()),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		logger:  zap.L().Named("process").With(zap.Uint("lot_id", lot.ID)),
 	}
 
+	p.timeline = newTimeline(clk, p.post)
+
 	if err := p.create(executor, lot, tx, startRule); err != nil {
+		p.Stop()
+		p.shutdown()
 		return nil, err
 	}
 
+	go p.loop()
+
 	return p, nil
 }
 
@@ -156,8 +218,101 @@ func (prc *process) LotID() uint {
 	return prc.lotID
 }
 
+func (prc *process) Now() time.Time {
+	return prc.clock.Now()
+}
+
+// Stop stops the process goroutine after the current handler, so it may
+// be called by the handler itself.
 func (prc *process) Stop() error {
-	return prc.timeline.Stop()
+	prc.stopOnce.Do(func() {
+		close(prc.done)
+	})
+	return nil
+}
+
+// Shutdown stops the process goroutine after the handler being run and
+// waits for it till the context is done. The timeline is stopped without
+// stopping the rule, so the rule is restored from the saved state by the
+// next owner of the lot.
+func (prc *process) Shutdown(ctx context.Context) error {
+	prc.Stop()
+
+	select {
+	case <-prc.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (prc *process) loop() {
+	defer close(prc.stopped)
+	defer prc.shutdown()
+
+	for {
+		for len(prc.pending) > 0 {
+			select {
+			case <-prc.done:
+				return
+			default:
+			}
+
+			handler := prc.pending[0]
+			prc.pending = prc.pending[1:]
+			handler()
+		}
+
+		select {
+		case handler := <-prc.mailbox:
+			handler()
+		case <-prc.done:
+			return
+		}
+	}
+}
+
+func (prc *process) shutdown() {
+	prc.stopReservation()
+	prc.timeline.Stop()
+}
+
+// call runs the handler in the process goroutine and waits for its result.
+// It must not be called from the process goroutine.
+func (prc *process) call(handler func() error) error {
+	result := make(chan error, 1)
+
+	select {
+	case prc.mailbox <- func() { result <- handler() }:
+	case <-prc.done:
+		return processStopped
+	}
+
+	return <-result
+}
+
+// callRule runs the handler of the command in the process goroutine.
+func (prc *process) callRule(
+	handler func(RuleProxy, Tx) error, act Action,
+) error {
+	return prc.call(func() error {
+		return prc.txRule(handler, act)
+	})
+}
+
+// post sends the handler to the mailbox without waiting for it, the handler
+// is dropped if the process is stopped. It is used by the timers.
+func (prc *process) post(handler func()) {
+	select {
+	case prc.mailbox <- handler:
+	case <-prc.done:
+	}
+}
+
+// deferred runs the handler in the process goroutine after the current one,
+// before the handlers of the mailbox.
+func (prc *process) deferred(handler func()) {
+	prc.pending = append(prc.pending, handler)
 }
 
 func (prc *process) PlaceBet(act PlaceBet) error {
@@ -165,7 +320,7 @@ func (prc *process) PlaceBet(act PlaceBet) error {
 		zap.Uint("executor_id", act.Executor().ID),
 	)
 
-	return prc.txRule(func(prx RuleProxy, tx *db.Tx) error {
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
 		lot := prx.ProcessLot()
 
 		if lot.BookedAt != nil {
@@ -173,6 +328,11 @@ func (prc *process) PlaceBet(act PlaceBet) error {
 			return lotAlreadyBooked
 		}
 
+		if err := prc.checkReservation(lot, act.Executor().ID); err != nil {
+			logger.Warn("lot reserved")
+			return err
+		}
+
 		if err := prc.currentRule.PlaceBet(act, prx); err != nil {
 			return err
 		}
@@ -181,13 +341,11 @@ func (prc *process) PlaceBet(act PlaceBet) error {
 
 		lot.UpdatePrice(act.Executor().ID)
 
-		historySvc := service.NewHistoryService(tx)
-
-		if err := historySvc.BetPlaced(act.Executor().ID, lot); err != nil {
+		if err := tx.BetPlaced(act.Executor().ID, lot); err != nil {
 			return err
 		}
 
-		return nil
+		return prc.proxyBet(prx, tx, act.Executor().ID)
 	}, act)
 }
 
@@ -196,7 +354,7 @@ func (prc *process) CancelBet(act Action) error {
 		zap.Uint("executor_id", act.Executor().ID),
 	)
 
-	return prc.txRule(func(prx RuleProxy, tx *db.Tx) error {
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
 		lot := prx.ProcessLot()
 
 		if lot.ConfirmedAt != nil {
@@ -209,6 +367,8 @@ func (prc *process) CancelBet(act Action) error {
 			return lotAlreadyCompleted
 		}
 
+		booked := lot.BookedAt != nil
+
 		if err := prc.currentRule.CancelBet(act, prx); err != nil {
 			return err
 		}
@@ -217,22 +377,154 @@ func (prc *process) CancelBet(act Action) error {
 
 		lot.UpdatePrice(act.Executor().ID)
 
-		historySvc := service.NewHistoryService(tx)
+		if booked {
+			if err := tx.BookingCanceled(act.Executor().ID, lot); err != nil {
+				return err
+			}
+			return nil
+		}
+
+		if err := tx.BetCanceled(act.Executor().ID, lot); err != nil {
+			return err
+		}
+
+		return nil
+	}, act)
+}
+
+func (prc *process) PlaceProxy(act PlaceProxy) error {
+	logger := prc.logger.Named("place_proxy").With(
+		zap.Uint("executor_id", act.Executor().ID),
+	)
+
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
+		lot := prx.ProcessLot()
+
+		if lot.BookedAt != nil {
+			logger.Warn("lot already booked")
+			return lotAlreadyBooked
+		}
+
+		if _, ok := prc.currentRule.(ProxyRule); !ok || prc.nextRule != nil {
+			logger.Warn("proxy bet disabled")
+			return proxyBetDisabled
+		}
+
+		if err := prc.checkReservation(lot, act.Executor().ID); err != nil {
+			logger.Warn("lot reserved")
+			return err
+		}
+
+		proxyBet, err := prx.UserProxyBet(lot.ID, act.Executor().ID)
+		if err != nil && err != service.ProxyBetNotFound {
+			logger.Error("get proxy bet failed", zap.Error(err))
+			return err
+		}
+
+		if proxyBet != nil {
+			if err := prx.DeleteProxyBet(proxyBet); err != nil {
+				logger.Error("delete proxy bet failed", zap.Error(err))
+				return err
+			}
+		}
+
+		newProxyBet := &core.ProxyBet{
+			Floor:  act.Floor(),
+			LotID:  lot.ID,
+			UserID: act.Executor().ID,
+		}
+
+		if err := prx.CreateProxyBet(newProxyBet); err != nil {
+			logger.Error("create proxy bet failed", zap.Error(err))
+			return err
+		}
+
+		prc.Sync(lot)
+
+		lot.UpdatePrice(act.Executor().ID)
+
+		return prc.proxyBet(prx, tx, act.Executor().ID)
+	}, act)
+}
+
+func (prc *process) CancelProxy(act Action) error {
+	logger := prc.logger.Named("cancel_proxy").With(
+		zap.Uint("executor_id", act.Executor().ID),
+	)
+
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
+		lot := prx.ProcessLot()
 
-		if err := historySvc.BetCanceled(act.Executor().ID, lot); err != nil {
+		proxyBet, err := prx.UserProxyBet(lot.ID, act.Executor().ID)
+		if err != nil {
+			logger.Warn("get proxy bet failed", zap.Error(err))
+			return err
+		}
+
+		if err := prx.DeleteProxyBet(proxyBet); err != nil {
+			logger.Error("delete proxy bet failed", zap.Error(err))
 			return err
 		}
 
+		prc.Sync(lot)
+
+		lot.UpdatePrice(act.Executor().ID)
+
 		return nil
 	}, act)
 }
 
+// proxyBet places the automatic bets of the current rule and records
+// them in the history in the order they were placed.
+func (prc *process) proxyBet(prx RuleProxy,
+	historySvc core.HistoryService, executorID uint) error {
+	logger := prc.logger.Named("proxy_bet")
+
+	rule, ok := prc.currentRule.(ProxyRule)
+	if !ok || prc.nextRule != nil {
+		return nil
+	}
+
+	// proxy bets wait for the end of the reservation
+	if prx.ProcessLot().Holder(prc.Now()) != 0 {
+		return nil
+	}
+
+	bets, err := rule.ProxyBet(prx)
+	if err != nil {
+		logger.Error("rule proxy bet failed", zap.Error(err))
+		return err
+	}
+
+	if len(bets) == 0 {
+		return nil
+	}
+
+	lot := prx.ProcessLot()
+
+	prc.Sync(lot)
+
+	for _, bet := range bets {
+		lot.CurrentPrice = bet.Value
+		lot.Bet = bet
+
+		if err := historySvc.ProxyBetPlaced(bet.UserID, lot); err != nil {
+			logger.Error("history proxy bet placed failed", zap.Error(err))
+			return err
+		}
+	}
+
+	lot.UpdatePrice(executorID)
+
+	return nil
+}
+
 func (prc *process) ConfirmLot(act ConfirmLot) error {
 	logger := prc.logger.Named("confirm_bet").With(
 		zap.Uint("executor_id", act.Executor().ID),
 	)
 
-	return prc.txRule(func(prx RuleProxy, tx *db.Tx) error {
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
 		lot := prx.ProcessLot()
 
 		if lot.ConfirmedAt != nil {
@@ -259,9 +551,7 @@ func (prc *process) ConfirmLot(act ConfirmLot) error {
 
 		lot.UpdatePrice(act.Executor().ID)
 
-		historySvc := service.NewHistoryService(tx)
-
-		if err := historySvc.LotConfirmed(act.Executor().ID, lot); err != nil {
+		if err := tx.LotConfirmed(act.Executor().ID, lot); err != nil {
 			return err
 		}
 
@@ -274,7 +564,7 @@ func (prc *process) AcceptBet(act AcceptBet) error {
 		zap.Uint("executor_id", act.Executor().ID),
 	)
 
-	return prc.txRule(func(prx RuleProxy, tx *db.Tx) error {
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
 		lot := prx.ProcessLot()
 
 		if lot.BookedAt != nil {
@@ -282,6 +572,13 @@ func (prc *process) AcceptBet(act AcceptBet) error {
 			return lotAlreadyBooked
 		}
 
+		if holder := lot.Holder(prc.Now()); holder != 0 {
+			if bet := lot.BetByID(act.BetID()); bet == nil || bet.UserID != holder {
+				logger.Warn("lot reserved")
+				return lotReserved
+			}
+		}
+
 		if err := prc.currentRule.AcceptBet(act, prx); err != nil {
 			return err
 		}
@@ -290,9 +587,7 @@ func (prc *process) AcceptBet(act AcceptBet) error {
 
 		lot.UpdatePrice(act.Executor().ID)
 
-		historySvc := service.NewHistoryService(tx)
-
-		if err := historySvc.LotBetAccept(act.Executor().ID, lot); err != nil {
+		if err := tx.LotBetAccept(act.Executor().ID, lot); err != nil {
 			return err
 		}
 
@@ -300,10 +595,43 @@ func (prc *process) AcceptBet(act AcceptBet) error {
 	}, act)
 }
 
+// Sync may be called from any goroutine.
 func (prc *process) Sync(lot *core.Lot) {
+	prc.mu.RLock()
+	defer prc.mu.RUnlock()
+
 	prc.currentRule.Sync(lot)
-	lot.End = prc.End()
-	lot.Rest = uint(lot.End.Sub(now()) / time.Second)
+	lot.End = prc.End().In(lot.Location())
+	lot.Rest = uint(lot.End.Sub(prc.Now()) / time.Second)
+}
+
+// Rule returns the name of the current rule, or of the next one if it is
+// already chosen. It may be called from any goroutine.
+func (prc *process) Rule() string {
+	prc.mu.RLock()
+	defer prc.mu.RUnlock()
+
+	if prc.nextRule != nil {
+		return prc.nextRule.Rule()
+	}
+	return prc.currentRule.Rule()
+}
+
+// State returns the state of the process as it is saved. The state is
+// read by the process goroutine, so it must not be called from there.
+func (prc *process) State() (*core.ProcessState, error) {
+	var state *core.ProcessState
+
+	err := prc.call(func() error {
+		var err error
+		state, err = prc.state()
+		return err
+	})
+	if err == processStopped {
+		return nil, ProcessNotFound
+	}
+
+	return state, err
 }
 
 func (prc *process) End() time.Time {
@@ -317,9 +645,17 @@ func (prc *process) End() time.Time {
 	return end
 }
 
+// Run stops the current rule, the rule is started after the current
+// handler.
 func (prc *process) Run(rule Rule) error {
+	prc.mu.Lock()
 	prc.nextRule = rule
+	prc.mu.Unlock()
+
 	prc.timeline.Stop()
+
+	prc.deferred(prc.advance)
+
 	return nil
 }
 
@@ -328,6 +664,7 @@ func (prc *process) Next() error {
 }
 
 func (prc *process) Complete() error {
+	prc.completed = true
 	return prc.prcSvc.Stop(prc)
 }
 
@@ -335,6 +672,31 @@ func (prc *process) Prolong(d time.Duration) {
 	prc.timeline.Prolong(d)
 }
 
+// Done returns the channel which is closed when the process is stopped.
+func (prc *process) Done() <-chan struct{} {
+	return prc.done
+}
+
+// Refresh syncs the lot with the rule and notifies clients. It returns
+// RuleNotActive if the rule is no longer the current rule of the process.
+func (prc *process) Refresh(rule Rule) error {
+	return prc.call(func() error {
+		if prc.currentRule != rule || prc.nextRule != nil {
+			return RuleNotActive
+		}
+
+		return prc.txRule(func(prx RuleProxy, tx Tx) error {
+			lot := prx.ProcessLot()
+
+			prc.Sync(lot)
+
+			prc.lotChanged(lot)
+
+			return nil
+		}, nil)
+	})
+}
+
 func (prc *process) start(rule Rule, startHandler func(rule Rule) error) error {
 	logger := prc.logger.Named("start")
 
@@ -342,19 +704,33 @@ func (prc *process) start(rule Rule, startHandler func(rule Rule) error) error {
 		return errors.New("rule can not be empty")
 	}
 
+	prc.pause()
+
+	prc.mu.Lock()
 	prc.currentRule = rule
+	prc.mu.Unlock()
+
+	logger.Debug("start",
+		zap.String("rule", rule.Rule()),
+		zap.Time("end", rule.Interval().End()),
+		zap.Duration("added", prc.added),
+	)
+
+	prc.timeline.Run(prc.Now(), rule.Interval().End(), prc.added, func() {
+		prc.complete(rule)
+	})
+
+	prc.added = 0
 
 	if err := startHandler(rule); err != nil {
 		logger.Error("rule start failed", zap.Error(err))
 		return err
 	}
 
-	go prc.run(rule)
-
 	return nil
 }
 
-func (prc *process) create(executor *core.User, lot *core.Lot, tx *db.Tx,
+func (prc *process) create(executor *core.User, lot *core.Lot, tx Tx,
 	startRule Rule) error {
 	logger := prc.logger.Named("restore")
 
@@ -365,17 +741,38 @@ func (prc *process) create(executor *core.User, lot *core.Lot, tx *db.Tx,
 	}
 
 	return prc.start(rule, func(rule Rule) error {
-		if err := rule.Start(newRuleProxy(prc, tx, executor, lot)); err != nil {
+		prx := newRuleProxy(prc, tx, executor, lot)
+
+		if err := prc.restoreReservation(prx, startRule); err != nil {
+			logger.Error("restore reservation failed", zap.Error(err))
+			return err
+		}
+
+		if err := rule.Start(prx); err != nil {
 			logger.Error("rule start failed", zap.Error(err))
 			return err
 		}
-		return nil
+
+		prc.Sync(lot)
+
+		if err := tx.AppendLotEvent(core.LotEventRuleStarted,
+			core.RootUserID, lot); err != nil {
+			logger.Error("append lot event failed", zap.Error(err))
+			return err
+		}
+
+		return prc.saveState(tx)
 	})
 }
 
 func (prc *process) next() Rule {
-	now := now().Add(1 * time.Second)
-	for _, rule := range prc.rules {
+	return prc.nextOf(prc.rules)
+}
+
+// nextOf returns the rule of the rules active at the time of the next rule.
+func (prc *process) nextOf(rules []Rule) Rule {
+	now := prc.Now().Add(lookahead)
+	for _, rule := range rules {
 		if rule.Interval().Contains(now) {
 			return rule
 		}
@@ -383,45 +780,113 @@ func (prc *process) next() Rule {
 	return nil
 }
 
-func (prc *process) run(rule Rule) error {
-	logger := prc.logger.Named("run")
-
-	start := now()
-	end := rule.Interval().End()
-
-	logger.Debug("start",
-		zap.String("rule", rule.Rule()),
-		zap.Time("start", start),
-		zap.Time("end", end),
-	)
-
-	complete := <-prc.timeline.Run(start, end)
+// complete stops the rule at the end of its timeline.
+func (prc *process) complete(rule Rule) {
+	logger := prc.logger.Named("complete")
 
 	logger.Debug("complete",
 		zap.String("rule", rule.Rule()),
 		zap.Time("start", rule.Interval().Start()),
 		zap.Time("end", prc.End()),
-		zap.Bool("complete", complete),
 	)
 
-	if complete {
-		if err := prc.ruleStop(rule); err != nil {
-			logger.Error("rule stop failed", zap.Error(err))
-		}
+	if err := prc.ruleStop(rule); err != nil {
+		logger.Error("rule stop failed", zap.Error(err))
+	}
+}
+
+// advance starts the next rule chosen by Run.
+func (prc *process) advance() {
+	logger := prc.logger.Named("advance")
+
+	next := prc.nextRule
+	if next == nil {
+		return
 	}
 
+	prc.mu.Lock()
+	prc.nextRule = nil
+	prc.mu.Unlock()
+
+	if err := prc.start(next, prc.ruleStart); err != nil {
+		logger.Error("start next rule failed", zap.Error(err))
+	}
+}
+
+// state returns the state of the process. If the next rule is already
+// chosen it is saved instead of the current one, so that it is started
+// after restart.
+func (prc *process) state() (*core.ProcessState, error) {
+	rule := prc.currentRule
+	end := prc.timeline.End().Add(-prc.timeline.Added())
+	added := prc.timeline.Added()
+
 	if prc.nextRule != nil {
-		if err := prc.start(prc.nextRule, prc.ruleStart); err != nil {
-			logger.Error("start next rule failed", zap.Error(err))
+		rule = prc.nextRule
+		end = rule.Interval().End()
+		added = 0
+	}
+
+	state := &core.ProcessState{
+		LotID:     prc.lotID,
+		Rule:      rule.Rule(),
+		RuleIndex: -1,
+		StartAt:   rule.Interval().Start(),
+		EndAt:     end,
+		Added:     added,
+	}
+
+	for i, r := range prc.rules {
+		if r == rule {
+			state.RuleIndex = i
+			break
 		}
-		prc.nextRule = nil
+	}
+
+	if r, ok := rule.(StateRule); ok {
+		data, err := json.Marshal(r.State())
+		if err != nil {
+			return nil, err
+		}
+		state.State = data
+	}
+
+	return state, nil
+}
+
+// saveState saves the state of the process, the state of the completed
+// process is deleted.
+func (prc *process) saveState(svc core.ProcessStateService) error {
+	logger := prc.logger.Named("save_state")
+
+	if prc.completed {
+		if err := svc.DeleteProcessState(prc.lotID); err != nil {
+			logger.Error("delete process state failed", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+
+	state, err := prc.state()
+	if err != nil {
+		logger.Error("process state failed", zap.Error(err))
+		return err
+	}
+
+	if err := svc.SaveProcessState(state); err != nil {
+		logger.Error("save process state failed", zap.Error(err))
+		return err
 	}
 
 	return nil
 }
 
 func (prc *process) ruleStart(rule Rule) error {
-	return prc.txRule(func(prx RuleProxy, tx *db.Tx) error {
+	return prc.txRule(func(prx RuleProxy, tx Tx) error {
+		if err := prc.clearReservation(prx); err != nil {
+			return err
+		}
+
 		if err := rule.Start(prx); err != nil {
 			return err
 		}
@@ -430,14 +895,19 @@ func (prc *process) ruleStart(rule Rule) error {
 
 		prc.Sync(lot)
 
-		prc.prcSvc.LotChanged(lot)
+		if err := tx.AppendLotEvent(core.LotEventRuleStarted,
+			core.RootUserID, lot); err != nil {
+			return err
+		}
+
+		prc.lotChanged(lot)
 
 		return nil
 	}, nil)
 }
 
 func (prc *process) ruleStop(rule Rule) error {
-	return prc.txRule(func(prx RuleProxy, tx *db.Tx) error {
+	return prc.txRule(func(prx RuleProxy, tx Tx) error {
 		lot := prx.ProcessLot()
 
 		prc.Sync(lot)
@@ -452,34 +922,38 @@ func (prc *process) ruleStop(rule Rule) error {
 		// 	return nil
 		// }
 
-		historySvc := service.NewHistoryService(tx)
-
 		if lot.CurrentBet() != nil && lot.CompletedAt != nil {
-			if err := historySvc.LotCompleted(lot.CurrentBet().UserID, lot); err != nil {
+			if err := tx.LotCompleted(lot.CurrentBet().UserID, lot); err != nil {
 				return err
 			}
 		} else if lot.CurrentBet() != nil && lot.BookedAt != nil {
-			if err := historySvc.LotBooked(lot.CurrentBet().UserID, lot); err != nil {
+			if err := tx.LotBooked(lot.CurrentBet().UserID, lot); err != nil {
 				return err
 			}
 		}
 
-		prc.prcSvc.LotChanged(lot)
+		prc.lotChanged(lot)
 
 		return nil
 	}, nil)
 }
 
-func (prc *process) tx(handler func(tx *db.Tx) error) error {
+func (prc *process) tx(handler func(tx Tx) error) error {
 	logger := prc.logger.Named("tx")
 
-	tx, err := prc.db.Begin()
+	tx, err := prc.store.Begin(prc.lotID)
+	if err == core.LotLeaseLost {
+		logger.Warn("lot lease lost")
+		prc.prcSvc.Stop(prc)
+		return err
+	}
 	if err != nil {
 		logger.Error("transaction begin failed", zap.Error(err))
 		return err
 	}
 
 	if err := handler(tx); err != nil {
+		prc.changed = nil
 		if err := tx.Rollback(); err != nil {
 			logger.Error("transaction rollback failed", zap.Error(err))
 		}
@@ -487,22 +961,32 @@ func (prc *process) tx(handler func(tx *db.Tx) error) error {
 	}
 
 	if err := tx.Commit(); err != nil {
+		prc.changed = nil
 		logger.Error("transaction commit failed", zap.Error(err))
 		return err
 	}
 
+	if lot := prc.changed; lot != nil {
+		prc.changed = nil
+		prc.prcSvc.LotChanged(lot)
+	}
+
 	return nil
 }
 
+// lotChanged notifies the clients about the lot after the transaction of
+// the handler is committed.
+func (prc *process) lotChanged(lot *core.Lot) {
+	prc.changed = lot
+}
+
 func (prc *process) txRule(
-	handler func(RuleProxy, *db.Tx) error, act Action,
+	handler func(RuleProxy, Tx) error, act Action,
 ) error {
 	logger := prc.logger.Named("txRule")
 
-	return prc.tx(func(tx *db.Tx) error {
-		svc := service.NewLotService(tx)
-
-		lot, err := svc.Lot(prc.lotID, false)
+	return prc.tx(func(tx Tx) error {
+		lot, err := tx.Lot(prc.lotID, false)
 		if err != nil {
 			logger.Error("get lot failed", zap.Error(err))
 			return err
@@ -524,6 +1008,6 @@ func (prc *process) txRule(
 			return err
 		}
 
-		return nil
+		return prc.saveState(tx)
 	})
 }
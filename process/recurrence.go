@@ -0,0 +1,187 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"gitlab/nefco/auction/core"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence returns the times of the lots created from a template.
+type Recurrence interface {
+	// Next returns the first time after t.
+	Next(t time.Time) time.Time
+}
+
+func ParseRecurrence(conf *core.Recurrence,
+	loc *time.Location) (Recurrence, error) {
+	switch conf.Type {
+	case core.RecurrenceDaily, core.RecurrenceWeekdays:
+		start, err := time.Parse("15:04", conf.Time)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &daily{
+			offset:   timeOfDay(start),
+			weekdays: make(map[time.Weekday]bool),
+			loc:      loc,
+		}
+
+		if conf.Type == core.RecurrenceDaily {
+			return r, nil
+		}
+
+		if len(conf.Weekdays) == 0 {
+			return nil, errors.New("the weekdays of the recurrence are empty")
+		}
+
+		for _, day := range conf.Weekdays {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("unknown weekday %q", day)
+			}
+			r.weekdays[weekday] = true
+		}
+
+		return r, nil
+	case core.RecurrenceCron:
+		return parseCron(conf.Cron, loc)
+	}
+
+	return nil, fmt.Errorf("unknown recurrence %q", conf.Type)
+}
+
+// daily recurs every day or on the given weekdays at the same wall clock
+// time.
+type daily struct {
+	offset   time.Duration
+	weekdays map[time.Weekday]bool
+	loc      *time.Location
+}
+
+func (r *daily) Next(t time.Time) time.Time {
+	d := t.In(r.loc)
+	for i := 0; i <= 7; i++ {
+		next := wallclock(d.AddDate(0, 0, i), r.offset, r.loc)
+		if !next.After(t) {
+			continue
+		}
+		if len(r.weekdays) > 0 && !r.weekdays[next.In(r.loc).Weekday()] {
+			continue
+		}
+		return next
+	}
+	return time.Time{}
+}
+
+// cron recurs by the expression of five fields: minutes, hours, days of
+// the month, months and days of the week. A field is *, a number, a range
+// a-b, a step */n or a-b/n, or a list of them separated by commas. Unlike
+// cron a day must match both the day of the month and of the week.
+type cron struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+	loc      *time.Location
+}
+
+func parseCron(expr string, loc *time.Location) (*cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q must have 5 fields", expr)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	sets := [5]map[int]bool{}
+
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %s", expr, err)
+		}
+		sets[i] = set
+	}
+
+	return &cron{sets[0], sets[1], sets[2], sets[3], sets[4], loc}, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step %q", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		from, to := min, max
+
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			v, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			from, to = v, v
+
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Next checks the minutes after t till the match, no longer than a few
+// years ahead, so an expression that never matches returns the zero time.
+func (c *cron) Next(t time.Time) time.Time {
+	d := t.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := d.AddDate(5, 0, 0)
+
+	for d.Before(limit) {
+		if !c.months[int(d.Month())] {
+			d = time.Date(d.Year(), d.Month()+1, 1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.days[d.Day()] || !c.weekdays[int(d.Weekday())] {
+			d = time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.hours[d.Hour()] {
+			d = time.Date(d.Year(), d.Month(), d.Day(), d.Hour()+1, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.minutes[d.Minute()] {
+			d = d.Add(time.Minute)
+			continue
+		}
+		return d.UTC()
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,75 @@
+package process
+
+import (
+	"gitlab/nefco/auction/core"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	loc := berlin(t)
+
+	cases := []struct {
+		conf     core.Recurrence
+		from, to time.Time
+	}{
+		// the wall clock time is kept over the change to the summer time
+		{
+			core.Recurrence{Type: core.RecurrenceDaily, Time: "09:30"},
+			time.Date(2024, 3, 30, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 31, 7, 30, 0, 0, time.UTC),
+		},
+		// the time of the recurrence itself is skipped
+		{
+			core.Recurrence{Type: core.RecurrenceWeekdays, Time: "09:30",
+				Weekdays: []string{"Mon", "fri"}},
+			time.Date(2024, 3, 29, 8, 30, 0, 0, time.UTC),
+			time.Date(2024, 4, 1, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			core.Recurrence{Type: core.RecurrenceCron, Cron: "0 */6 * * 1-5"},
+			time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			core.Recurrence{Type: core.RecurrenceCron, Cron: "15 10 1 * *"},
+			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 1, 9, 15, 0, 0, time.UTC),
+		},
+		// the expression which never matches
+		{
+			core.Recurrence{Type: core.RecurrenceCron, Cron: "0 0 31 2 *"},
+			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			time.Time{},
+		},
+	}
+
+	for _, c := range cases {
+		r, err := ParseRecurrence(&c.conf, loc)
+		if err != nil {
+			t.Errorf("%+v: %v", c.conf, err)
+			continue
+		}
+		if next := r.Next(c.from); !next.Equal(c.to) {
+			t.Errorf("%+v after %v: got %v, want %v", c.conf, c.from, next, c.to)
+		}
+	}
+}
+
+func TestRecurrenceInvalid(t *testing.T) {
+	confs := []core.Recurrence{
+		{Type: core.RecurrenceDaily, Time: "25:00"},
+		{Type: core.RecurrenceWeekdays, Time: "09:30"},
+		{Type: core.RecurrenceWeekdays, Time: "09:30", Weekdays: []string{"mo"}},
+		{Type: core.RecurrenceCron, Cron: "0 * * *"},
+		{Type: core.RecurrenceCron, Cron: "60 * * * *"},
+		{Type: core.RecurrenceCron, Cron: "*/0 * * * *"},
+		{Type: "monthly"},
+	}
+
+	for _, conf := range confs {
+		if _, err := ParseRecurrence(&conf, time.UTC); err == nil {
+			t.Errorf("%+v accepted", conf)
+		}
+	}
+}
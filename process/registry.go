@@ -0,0 +1,133 @@
+package process
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/errors"
+	"sort"
+	"sync"
+)
+
+var (
+	ProcessNotFound = errors.NotFound("Process not found")
+)
+
+// Registry is the set of the running processes by the lot. It is used by
+// the request goroutines and by the processes themselves when they are
+// completed, so it is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	pp       map[uint]Process
+	starting map[uint]chan struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		pp:       make(map[uint]Process),
+		starting: make(map[uint]chan struct{}),
+	}
+}
+
+func (r *Registry) Get(lotID uint) (Process, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	prc, ok := r.pp[lotID]
+	return prc, ok
+}
+
+// Set registers the process of the lot. It returns false if the lot already
+// has the process.
+func (r *Registry) Set(prc Process) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pp[prc.LotID()]; ok {
+		return false
+	}
+	r.pp[prc.LotID()] = prc
+	return true
+}
+
+// Lock waits until no process of the lot is being started and locks the
+// start of the process of the lot till unlock is called.
+func (r *Registry) Lock(lotID uint) (unlock func()) {
+	for {
+		r.mu.Lock()
+		wait, ok := r.starting[lotID]
+		if !ok {
+			done := make(chan struct{})
+			r.starting[lotID] = done
+			r.mu.Unlock()
+
+			return func() {
+				r.mu.Lock()
+				delete(r.starting, lotID)
+				r.mu.Unlock()
+				close(done)
+			}
+		}
+		r.mu.Unlock()
+
+		<-wait
+	}
+}
+
+// Remove unregisters the process. It returns false if the process is not
+// registered, for example when it is already replaced by the new process
+// of the lot.
+func (r *Registry) Remove(prc Process) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pp[prc.LotID()] != prc {
+		return false
+	}
+	delete(r.pp, prc.LotID())
+	return true
+}
+
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.pp)
+}
+
+// List returns the processes ordered by the lot.
+func (r *Registry) List() []Process {
+	r.mu.RLock()
+	pp := make([]Process, 0, len(r.pp))
+	for _, prc := range r.pp {
+		pp = append(pp, prc)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(pp, func(i, j int) bool {
+		return pp[i].LotID() < pp[j].LotID()
+	})
+
+	return pp
+}
+
+// CountByRule returns the number of the processes by their current rule.
+func (r *Registry) CountByRule() map[string]int {
+	count := make(map[string]int)
+	for _, prc := range r.List() {
+		count[prc.Rule()]++
+	}
+	return count
+}
+
+// State returns the state of the process of the lot.
+func (r *Registry) State(lotID uint) (*core.ProcessState, error) {
+	prc, ok := r.Get(lotID)
+	if !ok {
+		return nil, ProcessNotFound
+	}
+	return prc.State()
+}
+
+// Sync syncs the lots which have the running process.
+func (r *Registry) Sync(lots ...*core.Lot) {
+	for _, lot := range lots {
+		if prc, ok := r.Get(lot.ID); ok {
+			prc.Sync(lot)
+		}
+	}
+}
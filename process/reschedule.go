@@ -0,0 +1,162 @@
+package process
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/errors"
+
+	"go.uber.org/zap"
+)
+
+var (
+	lotRulesChangeBets = errors.BadRequest("Lot rules can not change the rule type of the lot with bets")
+)
+
+// ResumeRule is implemented by rules which run in the background while they
+// are the current rule. Resume is called instead of Start, when the rule
+// replaces the current rule of the same type by Reschedule. Pause is called
+// when the rule stops being the current rule.
+type ResumeRule interface {
+	Resume(prx RuleProxy) error
+	Pause()
+}
+
+// CheckReschedule returns lotRulesChangeBets if Reschedule would replace
+// the current rule of the lot with bets by the rule of another type. It is
+// called before the new rules of the lot are saved.
+func (prc *process) CheckReschedule(rules []Rule, lot *core.Lot) error {
+	return prc.call(func() error {
+		next, err := prc.replacement(rules)
+		if err != nil || next == nil {
+			return err
+		}
+
+		if current := prc.current(); next.Rule() != current.Rule() &&
+			len(lot.Bets) > 0 {
+			prc.logger.Warn("lot rules change bets",
+				zap.String("current", current.Rule()),
+				zap.String("next", next.Rule()),
+			)
+			return lotRulesChangeBets
+		}
+
+		return nil
+	})
+}
+
+// Reschedule replaces the rules of the running process after the new rules
+// of the lot are saved. The rule active now by the new rules replaces the
+// current one:
+//   - the rule of the same type is resumed without Start, so the bets and
+//     the booking of the lot are kept;
+//   - the rule of another type is started, as on restart, if the lot has no
+//     bets, otherwise the current rule is kept till its end.
+//
+// If the current rule was started by another rule, like confirm or hot, it
+// is not interrupted and the new rules are used when it is over.
+func (prc *process) Reschedule(rules []Rule) error {
+	logger := prc.logger.Named("reschedule")
+
+	return prc.call(func() error {
+		return prc.txRule(func(prx RuleProxy, tx Tx) error {
+			next, err := prc.replacement(rules)
+			if err != nil {
+				logger.Warn("rule not found")
+				return err
+			}
+
+			current := prc.current()
+
+			prc.rules = rules
+
+			if next == nil {
+				return nil
+			}
+
+			logger.Debug("reschedule",
+				zap.String("current", current.Rule()),
+				zap.String("next", next.Rule()),
+			)
+
+			if next.Rule() == current.Rule() {
+				return prc.resume(next, prx, tx)
+			}
+
+			// the bets were placed after the check of the new rules
+			if len(prx.ProcessLot().Bets) > 0 {
+				logger.Warn("lot rules change bets, current rule kept",
+					zap.String("current", current.Rule()),
+				)
+				return nil
+			}
+
+			return prc.Run(next)
+		}, nil)
+	})
+}
+
+// pause stops the background work of the current rule before it is
+// replaced.
+func (prc *process) pause() {
+	if r, ok := prc.currentRule.(ResumeRule); ok {
+		r.Pause()
+	}
+}
+
+// current returns the current rule or the next one if it is already chosen.
+func (prc *process) current() Rule {
+	if prc.nextRule != nil {
+		return prc.nextRule
+	}
+	return prc.currentRule
+}
+
+// replacement returns the rule of the new rules which replaces the current
+// rule. It returns nil if the current rule was started by another rule.
+func (prc *process) replacement(rules []Rule) (Rule, error) {
+	current := prc.current()
+
+	for _, rule := range prc.rules {
+		if rule == current {
+			if next := prc.nextOf(rules); next != nil {
+				return next, nil
+			}
+			return nil, errors.New("rule can not be empty")
+		}
+	}
+
+	return nil, nil
+}
+
+// resume replaces the current rule by the rule of the same type without
+// starting it.
+func (prc *process) resume(rule Rule, prx RuleProxy, tx Tx) error {
+	prc.pause()
+
+	prc.mu.Lock()
+	prc.currentRule = rule
+	prc.nextRule = nil
+	prc.mu.Unlock()
+
+	prc.timeline.Run(prc.Now(), rule.Interval().End(), 0, func() {
+		prc.complete(rule)
+	})
+
+	if r, ok := rule.(ResumeRule); ok {
+		if err := r.Resume(prx); err != nil {
+			return err
+		}
+	}
+
+	lot := prx.ProcessLot()
+
+	prc.Sync(lot)
+
+	if err := tx.AppendLotEvent(core.LotEventRuleStarted,
+		core.RootUserID, lot); err != nil {
+		return err
+	}
+
+	prc.lotChanged(lot)
+
+	return nil
+}
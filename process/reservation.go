@@ -0,0 +1,219 @@
+package process
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	lotReserved         = errors.BadRequest("Lot reserved by another user")
+	lotAlreadyReserved  = errors.BadRequest("Lot already reserved")
+	reservationDisabled = errors.BadRequest("Reservation disabled")
+	reservationNotFound = errors.BadRequest("Reservation not found")
+)
+
+// ReserveRule is implemented by rules in which the carrier can hold the
+// lot for a while, so that other carriers can't bet on it or get it
+// accepted.
+type ReserveRule interface {
+	Reservable() bool
+}
+
+func (prc *process) PlaceReservation(act Action, d time.Duration) error {
+	logger := prc.logger.Named("place_reservation").With(
+		zap.Uint("executor_id", act.Executor().ID),
+	)
+
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
+		lot := prx.ProcessLot()
+
+		if lot.BookedAt != nil {
+			logger.Warn("lot already booked")
+			return lotAlreadyBooked
+		}
+
+		rule, ok := prc.currentRule.(ReserveRule)
+		if !ok || !rule.Reservable() || prc.nextRule != nil {
+			logger.Warn("reservation disabled")
+			return reservationDisabled
+		}
+
+		n := prc.Now()
+
+		if lot.Holder(n) != 0 {
+			logger.Warn("lot already reserved")
+			return lotAlreadyReserved
+		}
+
+		till := n.Add(d)
+		if end := prc.End(); till.After(end) {
+			till = end
+		}
+
+		lot.Reserve(act.Executor().ID, till)
+
+		if err := prx.SaveLot(lot); err != nil {
+			logger.Error("save lot failed", zap.Error(err))
+			return err
+		}
+
+		prc.Sync(lot)
+
+		lot.UpdatePrice(act.Executor().ID)
+
+		if err := prx.LotReserved(act.Executor().ID, lot); err != nil {
+			logger.Error("history lot reserved failed", zap.Error(err))
+			return err
+		}
+
+		prc.watchReservation(till)
+
+		return nil
+	}, act)
+}
+
+func (prc *process) CancelReservation(act Action) error {
+	logger := prc.logger.Named("cancel_reservation").With(
+		zap.Uint("executor_id", act.Executor().ID),
+	)
+
+	return prc.callRule(func(prx RuleProxy, tx Tx) error {
+		lot := prx.ProcessLot()
+
+		if lot.Holder(prc.Now()) != act.Executor().ID {
+			logger.Warn("reservation not found")
+			return reservationNotFound
+		}
+
+		if err := prc.clearReservation(prx); err != nil {
+			return err
+		}
+
+		prc.Sync(lot)
+
+		lot.UpdatePrice(act.Executor().ID)
+
+		if err := prx.ReservationCanceled(act.Executor().ID, lot); err != nil {
+			logger.Error("history reservation canceled failed", zap.Error(err))
+			return err
+		}
+
+		// the proxy bets waited for the end of the reservation
+		return prc.proxyBet(prx, tx, act.Executor().ID)
+	}, act)
+}
+
+// checkReservation returns lotReserved if the lot is held by other user
+// than userID.
+func (prc *process) checkReservation(lot *core.Lot, userID uint) error {
+	if holder := lot.Holder(prc.Now()); holder != 0 && holder != userID {
+		return lotReserved
+	}
+	return nil
+}
+
+// clearReservation removes the reservation of the lot and stops its
+// expiration.
+func (prc *process) clearReservation(prx RuleProxy) error {
+	logger := prc.logger.Named("clear_reservation")
+
+	prc.stopReservation()
+
+	lot := prx.ProcessLot()
+
+	if lot.ReservedBy == nil {
+		return nil
+	}
+
+	lot.ClearReservation()
+
+	if err := prx.SaveLot(lot); err != nil {
+		logger.Error("save lot failed", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// watchReservation expires the reservation of the lot at the time.
+func (prc *process) watchReservation(till time.Time) {
+	prc.stopReservation()
+
+	timer := prc.clock.NewTimer(till.Sub(prc.Now()))
+	done := make(chan bool)
+
+	prc.reservation = done
+
+	go func() {
+		select {
+		case <-timer.C():
+			prc.post(func() {
+				if err := prc.expireReservation(); err != nil {
+					prc.logger.Error("expire reservation failed", zap.Error(err))
+				}
+			})
+		case <-done:
+			timer.Stop()
+		}
+	}()
+}
+
+func (prc *process) stopReservation() {
+	if prc.reservation != nil {
+		close(prc.reservation)
+		prc.reservation = nil
+	}
+}
+
+func (prc *process) expireReservation() error {
+	return prc.txRule(func(prx RuleProxy, tx Tx) error {
+		lot := prx.ProcessLot()
+
+		if lot.ReservedTill == nil || prc.Now().Before(*lot.ReservedTill) {
+			return nil
+		}
+
+		lot.ClearReservation()
+
+		if err := prx.SaveLot(lot); err != nil {
+			return err
+		}
+
+		prc.Sync(lot)
+
+		lot.UpdatePrice(lot.UserID)
+
+		if err := prx.ReservationExpired(core.RootUserID, lot); err != nil {
+			return err
+		}
+
+		if err := prc.proxyBet(prx, tx, lot.UserID); err != nil {
+			return err
+		}
+
+		prc.lotChanged(lot)
+
+		return nil
+	}, nil)
+}
+
+// restoreReservation keeps the reservation of the lot if the restored
+// rule is still in progress, otherwise the reservation is cleared.
+func (prc *process) restoreReservation(prx RuleProxy, startRule Rule) error {
+	lot := prx.ProcessLot()
+
+	if lot.ReservedBy == nil {
+		return nil
+	}
+
+	if rule, ok := startRule.(ReserveRule); ok && rule.Reservable() &&
+		lot.Holder(prc.Now()) != 0 {
+		prc.watchReservation(*lot.ReservedTill)
+		return nil
+	}
+
+	return prc.clearReservation(prx)
+}
@@ -4,17 +4,20 @@ package rule
 // - base
 //   - wait
 //   - pick
+//   - dutch
 //   - confirm
 //   - common
 //     - hot
 //     - simple
 //     	 - normal
 //     	 - extra
+//     	 - sealed
 
 import (
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/errors"
 	"gitlab/nefco/auction/process"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -29,6 +32,8 @@ var (
 	betAlreadyExist       = errors.BadRequest("Bet is already made by the current user")
 	betNotFound           = errors.BadRequest("Bet not found")
 	betOtherUser          = errors.BadRequest("Bid made by another user")
+	betPriceChanged       = errors.BadRequest("Bet price changed")
+	betAboveReserve       = errors.BadRequest("Bet above the reserve price")
 )
 
 type base struct {
@@ -45,6 +50,11 @@ func newBase(name string, interval process.Interval) *base {
 	}
 }
 
+// now returns the current time of the clock of the rule interval.
+func (rule *base) now() time.Time {
+	return rule.interval.Clock().Now()
+}
+
 func (rule *base) Rule() string {
 	return rule.name
 }
@@ -3,6 +3,7 @@ package rule
 import (
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/process"
+	"sort"
 	"time"
 
 	"go.uber.org/zap"
@@ -11,7 +12,7 @@ import (
 type commonConfig interface {
 	basePrice() uint
 	price() uint
-	betStep() uint
+	betStep(price uint) uint
 	confirmDuration() time.Duration
 	extra() bool
 }
@@ -47,7 +48,13 @@ func (rule *common) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error
 		}
 	}
 
-	if ((curPrice-act.Value())%rule.betStep()) != 0 &&
+	step := rule.betStep(curPrice)
+	if step == 0 {
+		logger.Warn("bet step not set")
+		return betStepInvalid
+	}
+
+	if ((curPrice-act.Value())%step) != 0 &&
 		act.Value() != rule.basePrice() {
 		logger.Warn("invalid bid step")
 		return betStepInvalid
@@ -96,9 +103,120 @@ func (rule *common) CancelBet(act process.Action, prx process.RuleProxy) error {
 	return nil
 }
 
+// proxyBets bets one bet step below the current bet on behalf of the users
+// with a proxy bet until their floors are reached. The bidding is simulated
+// step by step and only the last bet of every user is saved.
+func (rule *common) proxyBets(prx process.RuleProxy) ([]*core.Bet, error) {
+	logger := rule.logger.Named("proxy_bets")
+
+	lot := prx.ProcessLot()
+
+	curBet := lot.CurrentBet()
+	if curBet == nil || curBet.Winner || rule.betStep(curBet.Value) == 0 {
+		return nil, nil
+	}
+
+	proxyBets, err := prx.ProxyBets(lot.ID)
+	if err != nil {
+		logger.Error("get proxy bets failed", zap.Error(err))
+		return nil, err
+	}
+
+	value, userID := curBet.Value, curBet.UserID
+
+	values := make(map[uint]uint)
+	users := []uint{}
+
+	for value > rule.betStep(value) {
+		next := value - rule.betStep(value)
+
+		var proxyBet *core.ProxyBet
+		for _, p := range proxyBets {
+			if p.UserID != userID && p.Floor <= next {
+				proxyBet = p
+				break
+			}
+		}
+
+		if proxyBet == nil {
+			break
+		}
+
+		value, userID = next, proxyBet.UserID
+
+		if _, ok := values[userID]; !ok {
+			users = append(users, userID)
+		}
+		values[userID] = value
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return values[users[i]] > values[users[j]]
+	})
+
+	bets := make([]*core.Bet, 0, len(users))
+
+	for _, userID := range users {
+		newBet := &core.Bet{
+			Value:  values[userID],
+			Auto:   true,
+			LotID:  lot.ID,
+			UserID: userID,
+		}
+
+		if err := prx.CreateBet(newBet); err != nil {
+			logger.Error("create bet failed", zap.Error(err))
+			return nil, err
+		}
+
+		lot.AddBet(newBet)
+
+		bets = append(bets, newBet)
+	}
+
+	return bets, nil
+}
+
+// prolong extends the rule by the prolong duration if the bet is placed in
+// the last moment. The prolongation is cut, so that the end of the rule
+// never passes the max duration from its start. The rule is not prolonged
+// if the last moment is zero.
+func (rule *common) prolong(prx process.RuleProxy, lastMoment time.Duration,
+	prolongDuration time.Duration, maxDuration time.Duration) {
+	logger := rule.logger.Named("prolong")
+
+	if prx.End().Sub(rule.now()) >= lastMoment {
+		return
+	}
+
+	maxTime := rule.interval.Start().Add(maxDuration)
+
+	d := prolongDuration
+	if rest := maxTime.Sub(prx.End()); rest < d {
+		d = rest
+	}
+
+	if d <= 0 {
+		return
+	}
+
+	prx.Prolong(d)
+
+	logger.Debug("prolong", zap.Duration("duration", d))
+}
+
+func (rule *common) Reservable() bool {
+	return true
+}
+
 func (rule *common) Sync(lot *core.Lot) {
 	lot.BasePrice = rule.basePrice()
-	lot.BetStep = rule.betStep()
+	price := rule.price()
+	if curBet := lot.CurrentBet(); curBet != nil {
+		price = curBet.Value
+	}
+
+	lot.BetStep = rule.betStep(price)
 	lot.RulePrice = rule.price()
 	rule.base.Sync(lot)
 }
@@ -111,7 +229,7 @@ func (rule *common) Stop(prx process.RuleProxy) error {
 	curBet := lot.CurrentBet()
 
 	if curBet != nil {
-		if curBet.Value <= rule.price() {
+		if curBet.Value <= rule.price() && lot.Reserved(curBet) {
 			curBet.Winner = true
 
 			if err := prx.SaveBet(curBet); err != nil {
@@ -119,7 +237,7 @@ func (rule *common) Stop(prx process.RuleProxy) error {
 				return err
 			}
 
-			n := now()
+			n := rule.now()
 
 			lot.BookedAt = &n
 
@@ -133,7 +251,7 @@ func (rule *common) Stop(prx process.RuleProxy) error {
 				return err
 			}
 
-			rule, err := NewConfirm(rule.confirmDuration())
+			rule, err := NewConfirm(rule.interval.Clock(), rule.confirmDuration())
 			if err != nil {
 				logger.Error("confirm rule failed", zap.Error(err))
 				return err
@@ -152,7 +270,7 @@ func (rule *common) Stop(prx process.RuleProxy) error {
 			return err
 		}
 
-		logger.Info("bet must be less than the price",
+		logger.Info("bet must be less than the price or the reserve",
 			zap.Uint("price", rule.price()),
 			zap.Uint("value", curBet.Value),
 		)
@@ -10,6 +10,10 @@ type Config struct {
 	Normal *NormalConfig `mapstructure:"normal"`
 	Extra  *ExtraConfig  `mapstructure:"extra"`
 	Pick   *PickConfig   `mapstructure:"pick"`
+	Dutch  *DutchConfig  `mapstructure:"dutch"`
+	Sealed *SealedConfig `mapstructure:"sealed"`
+	// Custom содержит настройки правил, добавленных через Register
+	Custom map[string]interface{} `mapstructure:"custom"`
 }
 
 func DefaultConfig() *Config {
@@ -31,5 +35,14 @@ func DefaultConfig() *Config {
 			BetStep:         500,
 			ConfirmDuration: DefaultConfirmDuration,
 		},
+		Dutch: &DutchConfig{
+			PriceStep:       500,
+			StepDuration:    5 * time.Minute,
+			ConfirmDuration: DefaultConfirmDuration,
+		},
+		Sealed: &SealedConfig{
+			ConfirmDuration: DefaultConfirmDuration,
+		},
+		Custom: map[string]interface{}{},
 	}
 }
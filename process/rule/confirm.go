@@ -1,6 +1,8 @@
 package rule
 
 import (
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/process"
 	"time"
 
@@ -11,17 +13,30 @@ const Confirm = "confirm"
 
 type confirm struct {
 	*base
+	offer uint
 }
 
-func NewConfirm(duration time.Duration) (*confirm, error) {
-	n := now()
+// confirmState is the state of the confirm rule saved with the process.
+type confirmState struct {
+	Offer uint `json:"offer"`
+}
+
+func NewConfirm(clk clock.Clock, duration time.Duration) (*confirm, error) {
+	return newConfirmAt(clk.Now(), duration, clk)
+}
 
-	interval, err := process.NewInterval(n, duration)
+func newConfirmAt(start time.Time, duration time.Duration,
+	clk clock.Clock) (*confirm, error) {
+	interval, err := process.NewPeriod(start, duration, clk)
 	if err != nil {
 		return nil, err
 	}
 
-	return &confirm{newBase(Confirm, interval)}, nil
+	return &confirm{base: newBase(Confirm, interval)}, nil
+}
+
+func (rule *confirm) State() interface{} {
+	return &confirmState{rule.offer}
 }
 
 func (rule *confirm) Stop(prx process.RuleProxy) error {
@@ -29,6 +44,22 @@ func (rule *confirm) Stop(prx process.RuleProxy) error {
 
 	lot := prx.ProcessLot()
 
+	if rule.offer < lot.Fallback {
+		ok, err := rule.fallback(prx)
+		if err != nil {
+			logger.Error("fallback failed", zap.Error(err))
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	var carrierID uint
+	if bet := lot.CurrentBet(); bet != nil {
+		carrierID = bet.UserID
+	}
+
 	lot.BookedAt = nil
 
 	if err := prx.SaveLot(lot); err != nil {
@@ -48,7 +79,7 @@ func (rule *confirm) Stop(prx process.RuleProxy) error {
 		return err
 	}
 
-	if err := prx.LotNoConfirm(lot.UserID, lot); err != nil {
+	if err := prx.LotNoConfirm(carrierID, lot); err != nil {
 		logger.Error("history lot not confirm failed")
 		return err
 	}
@@ -61,6 +92,98 @@ func (rule *confirm) Stop(prx process.RuleProxy) error {
 	return nil
 }
 
+// fallback offers the lot to the best bidder after the winner who did not
+// confirm it. The offer gets its own confirm window. It returns false if
+// there is no other bidder.
+func (rule *confirm) fallback(prx process.RuleProxy) (bool, error) {
+	logger := rule.logger.Named("fallback")
+
+	lot := prx.ProcessLot()
+
+	winner := lot.CurrentBet()
+	if winner == nil || !winner.Winner {
+		return false, nil
+	}
+
+	var runnerUp *core.Bet
+	failed := []*core.Bet{}
+
+	for _, bet := range lot.Bets {
+		if bet.UserID == winner.UserID {
+			failed = append(failed, bet)
+			continue
+		}
+		if !lot.Reserved(bet) {
+			continue
+		}
+		if runnerUp == nil || bet.Value < runnerUp.Value ||
+			(bet.Value == runnerUp.Value && bet.CreatedAt.Before(runnerUp.CreatedAt)) {
+			runnerUp = bet
+		}
+	}
+
+	if runnerUp == nil {
+		return false, nil
+	}
+
+	if err := prx.LotNoConfirm(winner.UserID, lot); err != nil {
+		logger.Error("history lot not confirm failed")
+		return false, err
+	}
+
+	for _, bet := range failed {
+		if err := prx.DeleteBet(bet); err != nil {
+			logger.Error("delete bet failed", zap.Error(err))
+			return false, err
+		}
+		lot.RemoveBet(bet)
+	}
+
+	runnerUp.Winner = true
+	runnerUp.Settled = nil
+
+	if err := prx.SaveBet(runnerUp); err != nil {
+		logger.Error("save bet failed", zap.Error(err))
+		return false, err
+	}
+
+	n := rule.now()
+
+	lot.BookedAt = &n
+
+	if err := prx.SaveLot(lot); err != nil {
+		logger.Error("save lot failed", zap.Error(err))
+		return false, err
+	}
+
+	if err := prx.SetDateBook(n, runnerUp); err != nil {
+		logger.Error("set date book failed", zap.Error(err))
+		return false, err
+	}
+
+	lot.UpdatePrice(lot.UserID)
+
+	r, err := newConfirmAt(n, rule.interval.Duration(), rule.interval.Clock())
+	if err != nil {
+		logger.Error("confirm rule failed", zap.Error(err))
+		return false, err
+	}
+
+	r.offer = rule.offer + 1
+
+	if err := prx.Run(r); err != nil {
+		logger.Error("run rule failed", zap.Error(err))
+		return false, err
+	}
+
+	logger.Debug("offer runner-up",
+		zap.Uint("offer", r.offer),
+		zap.Uint("user_id", runnerUp.UserID),
+	)
+
+	return true, nil
+}
+
 func (rule *confirm) CancelBet(act process.Action, prx process.RuleProxy) error {
 	logger := rule.logger.Named("cancel_bet")
 
@@ -132,7 +255,7 @@ func (rule *confirm) ConfirmLot(act process.ConfirmLot, prx process.RuleProxy) e
 		return err
 	}
 
-	n := now()
+	n := rule.now()
 
 	lot.ConfirmedAt = &n
 	lot.Confirm = act.Info()
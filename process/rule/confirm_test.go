@@ -0,0 +1,70 @@
+package rule
+
+import (
+	"context"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"testing"
+)
+
+// TestConfirmFallback offers the lot to the runner-up after the winner,
+// who did not confirm it, in the new confirm window. The lot is freed
+// after the last offer.
+func TestConfirmFallback(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	lot.Fallback = 1
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	placeBets(t, prc, lot.ID, 9500, 9000, 8500)
+
+	if st := step(t, clk, prc); st.Rule != Confirm {
+		t.Fatalf("rule = %s, want %s", st.Rule, Confirm)
+	}
+	if bet := winner(s.lot(lot.ID)); bet == nil || bet.UserID != 3 {
+		t.Fatalf("winner = %+v, want the lowest bet", bet)
+	}
+
+	st := step(t, clk, prc)
+	if st.Rule != Confirm || !st.StartAt.Equal(at(12, 0)) {
+		t.Fatalf("rule = %s at %s, want %s at 12:00", st.Rule, st.StartAt, Confirm)
+	}
+
+	offered := s.lot(lot.ID)
+	if bet := winner(offered); bet == nil || bet.UserID != 2 || bet.Value != 9000 {
+		t.Fatalf("winner = %+v, want the runner-up", bet)
+	}
+	for _, bet := range offered.Bets {
+		if bet.UserID == 3 {
+			t.Fatalf("bet %+v of the winner not deleted", bet)
+		}
+	}
+	if offered.BookedAt == nil || !offered.BookedAt.Equal(at(12, 0)) {
+		t.Fatalf("booked at = %v, want 12:00", offered.BookedAt)
+	}
+
+	if st := step(t, clk, prc); st.Rule != Wait {
+		t.Fatalf("rule = %s, want %s", st.Rule, Wait)
+	}
+
+	free := s.lot(lot.ID)
+	if free.BookedAt != nil || len(free.Bets) != 0 {
+		t.Fatalf("lot booked at %v with %d bets, want free lot",
+			free.BookedAt, len(free.Bets))
+	}
+
+	noConfirm := 0
+	for _, action := range s.actions() {
+		if action == core.HistoryLotNoConfirm {
+			noConfirm++
+		}
+	}
+	if noConfirm != 2 {
+		t.Fatalf("history = %v, want 2 lots not confirmed", s.actions())
+	}
+}
@@ -0,0 +1,259 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/process"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const Dutch = "dutch"
+
+type DutchConfig struct {
+	StartPrice      uint          `json:"start_price" validate:"required,gt=0"`
+	MinPrice        uint          `json:"min_price" validate:"required,gt=0,ltefield=StartPrice"`
+	PriceStep       uint          `json:"price_step" mapstructure:"price_step" validate:"gt=0"`
+	StepDuration    time.Duration `mapstructure:"step_duration" validate:"gt=0"`
+	ConfirmDuration time.Duration `mapstructure:"confirm_duration" validate:"gt=0"`
+}
+
+type dutch struct {
+	*base
+	*DutchConfig
+	done chan struct{}
+}
+
+func newDutch(interval process.Interval, defaultConfig DutchConfig) *dutch {
+	return &dutch{base: newBase(Dutch, interval), DutchConfig: &defaultConfig}
+}
+
+func (rule *dutch) UnmarshalJSON(data []byte) error {
+	config := struct {
+		*DutchConfig
+		StepDuration    core.Duration `json:"step_duration"`
+		ConfirmDuration core.Duration `json:"confirm_duration"`
+	}{
+		DutchConfig:     rule.DutchConfig,
+		StepDuration:    core.Duration(rule.DutchConfig.StepDuration),
+		ConfirmDuration: core.Duration(rule.DutchConfig.ConfirmDuration),
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("config dutch duration unmarshal error: %s", err)
+	}
+
+	rule.DutchConfig.StepDuration = time.Duration(config.StepDuration)
+	rule.DutchConfig.ConfirmDuration = time.Duration(config.ConfirmDuration)
+
+	return nil
+}
+
+func (rule dutch) MarshalJSON() ([]byte, error) {
+	config := struct {
+		*DutchConfig
+		StepDuration    core.Duration `json:"step_duration"`
+		ConfirmDuration core.Duration `json:"confirm_duration"`
+	}{
+		DutchConfig:     rule.DutchConfig,
+		StepDuration:    core.Duration(rule.DutchConfig.StepDuration),
+		ConfirmDuration: core.Duration(rule.DutchConfig.ConfirmDuration),
+	}
+
+	data, err := json.Marshal(&config)
+	if err != nil {
+		return nil, fmt.Errorf("config dutch marshal error: %s", err)
+	}
+
+	return data, nil
+}
+
+func (rule *dutch) Config() interface{} {
+	return rule.DutchConfig
+}
+
+// price returns the live price of the lot: the start price lowered by
+// the price step for every elapsed step duration, but not below the
+// min price.
+func (rule *dutch) price(t time.Time) uint {
+	elapsed := t.Sub(rule.interval.Start())
+	if elapsed <= 0 {
+		return rule.StartPrice
+	}
+
+	drop := uint(elapsed/rule.StepDuration) * rule.PriceStep
+
+	if drop >= rule.StartPrice-rule.MinPrice {
+		return rule.MinPrice
+	}
+
+	return rule.StartPrice - drop
+}
+
+func (rule *dutch) Reservable() bool {
+	return true
+}
+
+func (rule *dutch) Sync(lot *core.Lot) {
+	price := rule.price(rule.now())
+	lot.BetStep = rule.PriceStep
+	lot.BasePrice = rule.StartPrice
+	lot.CurrentPrice = price
+	lot.RulePrice = price
+	rule.base.Sync(lot)
+}
+
+func (rule *dutch) Start(prx process.RuleProxy) error {
+	logger := rule.logger.Named("start")
+
+	lot := prx.ProcessLot()
+
+	if lot.BookedAt != nil {
+		lot.BookedAt = nil
+
+		if err := prx.SaveLot(lot); err != nil {
+			logger.Error("save lot failed", zap.Error(err))
+			return err
+		}
+
+		if err := prx.ResetDateBook(); err != nil {
+			logger.Error("reset date book failed", zap.Error(err))
+			return err
+		}
+	}
+
+	if err := prx.ClearBets(lot.ID); err != nil {
+		logger.Error("clear bets failed", zap.Error(err))
+		return err
+	}
+
+	lot.ClearBets()
+
+	return rule.Resume(prx)
+}
+
+// Resume starts the ticks of the price drops.
+func (rule *dutch) Resume(prx process.RuleProxy) error {
+	rule.Pause()
+
+	done := make(chan struct{})
+	rule.done = done
+
+	go rule.tick(prx, done)
+
+	return nil
+}
+
+// Pause stops the ticks of the price drops.
+func (rule *dutch) Pause() {
+	if rule.done != nil {
+		close(rule.done)
+		rule.done = nil
+	}
+}
+
+// tick notifies clients about every price drop till the rule is paused or
+// the process is stopped.
+func (rule *dutch) tick(prx process.RuleProxy, done <-chan struct{}) {
+	logger := rule.logger.Named("tick")
+
+	end := rule.interval.End()
+
+	for {
+		next := rule.interval.Start()
+		for !next.After(rule.now()) {
+			next = next.Add(rule.StepDuration)
+		}
+
+		if !next.Before(end) || rule.price(next) == rule.price(rule.now()) {
+			return
+		}
+
+		timer := rule.interval.Clock().NewTimer(next.Sub(rule.now()))
+
+		select {
+		case <-timer.C():
+		case <-done:
+			timer.Stop()
+			return
+		case <-prx.Done():
+			timer.Stop()
+			return
+		}
+
+		if err := prx.Refresh(rule); err != nil {
+			if err != process.RuleNotActive {
+				logger.Error("refresh failed", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+func (rule *dutch) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
+	logger := rule.logger.Named("place_bet")
+
+	lot := prx.ProcessLot()
+
+	// the value is the price seen by the carrier, the lot is booked at the
+	// live price only if it has not dropped since
+	price := rule.price(rule.now())
+
+	if act.Value() != price {
+		logger.Warn("bet price changed",
+			zap.Uint("value", act.Value()),
+			zap.Uint("price", price),
+		)
+		return betPriceChanged
+	}
+
+	newBet := &core.Bet{
+		Value:  price,
+		Winner: true,
+		LotID:  lot.ID,
+		UserID: act.Executor().ID,
+	}
+
+	// the lot is not booked above the reserve, the carrier may bet again
+	// when the price drops to it
+	if !lot.Reserved(newBet) {
+		logger.Warn("bet above the reserve price", zap.Uint("price", price))
+		return betAboveReserve
+	}
+
+	if err := prx.CreateBet(newBet); err != nil {
+		logger.Error("create bet failed", zap.Error(err))
+		return err
+	}
+
+	lot.AddBet(newBet)
+
+	n := rule.now()
+
+	lot.BookedAt = &n
+
+	if err := prx.SaveLot(lot); err != nil {
+		logger.Error("save lot failed", zap.Error(err))
+		return err
+	}
+
+	if err := prx.SetDateBook(n, newBet); err != nil {
+		logger.Error("set date book failed", zap.Error(err))
+		return err
+	}
+
+	r, err := NewConfirm(rule.interval.Clock(), rule.ConfirmDuration)
+	if err != nil {
+		logger.Error("confirm rule failed", zap.Error(err))
+		return err
+	}
+
+	if err := prx.Run(r); err != nil {
+		logger.Error("run rule failed", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
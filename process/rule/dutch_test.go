@@ -0,0 +1,83 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"testing"
+	"time"
+)
+
+// TestDutchPriceDrop lowers the price of the lot by the step down to the
+// min price and books the lot at the live price, if it is not above the
+// reserve price.
+func TestDutchPriceDrop(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	lot.Rules[0].Type = Dutch
+	lot.Rules[0].Props = json.RawMessage(`{"start_price":10000,` +
+		`"min_price":8000,"price_step":500,"step_duration":"5m"}`)
+	reserve := uint(8500)
+	lot.ReservePrice = &reserve
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	if st := step(t, clk, prc); st.Rule != Dutch {
+		t.Fatalf("rule = %s, want %s", st.Rule, Dutch)
+	}
+
+	price := func() uint {
+		lot := s.lot(lot.ID)
+		prc.Sync(lot)
+		return lot.CurrentPrice
+	}
+
+	if p := price(); p != 10000 {
+		t.Fatalf("price = %d, want the start price", p)
+	}
+
+	clk.Advance(12 * time.Minute)
+
+	if p := price(); p != 9000 {
+		t.Fatalf("price = %d, want 9000 after two steps", p)
+	}
+
+	bet := func(value uint) error {
+		return prc.PlaceBet(&testAct{user: testUser(1), lotID: lot.ID,
+			value: value})
+	}
+
+	if err := bet(9500); err != betPriceChanged {
+		t.Fatalf("bet at the old price: got %v, want %v", err, betPriceChanged)
+	}
+	if err := bet(9000); err != betAboveReserve {
+		t.Fatalf("bet above the reserve: got %v, want %v", err, betAboveReserve)
+	}
+
+	clk.Advance(30 * time.Minute)
+
+	if p := price(); p != 8000 {
+		t.Fatalf("price = %d, want the min price", p)
+	}
+
+	if err := bet(8000); err != nil {
+		t.Fatalf("bet: %v", err)
+	}
+
+	if st := state(t, prc); st.Rule != Confirm {
+		t.Fatalf("rule = %s, want %s", st.Rule, Confirm)
+	}
+
+	final := s.lot(lot.ID)
+	if final.BookedAt == nil || len(final.Bets) != 1 ||
+		final.Bets[0].Value != 8000 || !final.Bets[0].Winner {
+		t.Fatalf("lot booked at %v with bets %v", final.BookedAt, final.Bets)
+	}
+
+	if actions := s.actions(); actions[len(actions)-1] != core.HistoryBetPlaced {
+		t.Fatalf("history = %v, want %s last", actions, core.HistoryBetPlaced)
+	}
+}
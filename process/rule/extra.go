@@ -13,11 +13,15 @@ import (
 const Extra = "extra"
 
 type ExtraConfig struct {
-	BetStep         uint          `json:"bet_step" mapstructure:"bet_step"`
+	BetStep         uint          `json:"bet_step" mapstructure:"bet_step" validate:"gt=0"`
+	BetSteps        BetSteps      `json:"bet_steps" mapstructure:"bet_steps" validate:"dive"`
 	BasePrice       uint          `json:"base_price" validate:"required,gt=0"`
 	ExtraPrice      uint          `json:"extra_price" db:"extra_price" validate:"required,gt=0"`
 	HotCount        uint          `json:"hot_count" mapstructure:"hot_count" validate:"gt=0"`
 	HotDuration     time.Duration `mapstructure:"hot_duration" validate:"gt=0"`
+	LastMoment      time.Duration `mapstructure:"last_moment" validate:"gte=0"`
+	ProlongDuration time.Duration `mapstructure:"prolong_duration" validate:"gte=0"`
+	MaxDuration     time.Duration `mapstructure:"max_duration" validate:"gte=0"`
 	ConfirmDuration time.Duration `mapstructure:"confirm_duration" validate:"gt=0"`
 }
 
@@ -29,8 +33,8 @@ func (conf ExtraConfig) price() uint {
 	return conf.ExtraPrice
 }
 
-func (conf ExtraConfig) betStep() uint {
-	return conf.BetStep
+func (conf ExtraConfig) betStep(price uint) uint {
+	return conf.BetSteps.step(price, conf.BetStep)
 }
 
 func (conf ExtraConfig) confirmDuration() time.Duration {
@@ -47,6 +51,7 @@ type extra struct {
 }
 
 func newExtra(interval process.Interval, defaultConfig ExtraConfig) *extra {
+	defaultConfig.BetSteps = defaultConfig.BetSteps.clone()
 	return &extra{
 		simple:      newSimple(Extra, interval, &defaultConfig),
 		ExtraConfig: &defaultConfig,
@@ -57,10 +62,16 @@ func (rule *extra) UnmarshalJSON(data []byte) error {
 	config := struct {
 		*ExtraConfig
 		HotDuration     core.Duration `json:"hot_duration"`
+		LastMoment      core.Duration `json:"last_moment"`
+		ProlongDuration core.Duration `json:"prolong_duration"`
+		MaxDuration     core.Duration `json:"max_duration"`
 		ConfirmDuration core.Duration `json:"confirm_duration"`
 	}{
 		ExtraConfig:     rule.ExtraConfig,
 		HotDuration:     core.Duration(rule.ExtraConfig.HotDuration),
+		LastMoment:      core.Duration(rule.ExtraConfig.LastMoment),
+		ProlongDuration: core.Duration(rule.ExtraConfig.ProlongDuration),
+		MaxDuration:     core.Duration(rule.ExtraConfig.MaxDuration),
 		ConfirmDuration: core.Duration(rule.ExtraConfig.ConfirmDuration),
 	}
 
@@ -69,6 +80,9 @@ func (rule *extra) UnmarshalJSON(data []byte) error {
 	}
 
 	rule.ExtraConfig.HotDuration = time.Duration(config.HotDuration)
+	rule.ExtraConfig.LastMoment = time.Duration(config.LastMoment)
+	rule.ExtraConfig.ProlongDuration = time.Duration(config.ProlongDuration)
+	rule.ExtraConfig.MaxDuration = time.Duration(config.MaxDuration)
 	rule.ExtraConfig.ConfirmDuration = time.Duration(config.ConfirmDuration)
 
 	return nil
@@ -78,10 +92,16 @@ func (rule extra) MarshalJSON() ([]byte, error) {
 	config := struct {
 		*ExtraConfig
 		HotDuration     core.Duration `json:"hot_duration"`
+		LastMoment      core.Duration `json:"last_moment"`
+		ProlongDuration core.Duration `json:"prolong_duration"`
+		MaxDuration     core.Duration `json:"max_duration"`
 		ConfirmDuration core.Duration `json:"confirm_duration"`
 	}{
 		ExtraConfig:     rule.ExtraConfig,
 		HotDuration:     core.Duration(rule.ExtraConfig.HotDuration),
+		LastMoment:      core.Duration(rule.ExtraConfig.LastMoment),
+		ProlongDuration: core.Duration(rule.ExtraConfig.ProlongDuration),
+		MaxDuration:     core.Duration(rule.ExtraConfig.MaxDuration),
 		ConfirmDuration: core.Duration(rule.ExtraConfig.ConfirmDuration),
 	}
 
@@ -119,7 +139,7 @@ func (rule *extra) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
 	}
 
 	if uint(len(unique)) >= rule.HotCount {
-		rule, err := newHot(rule.ExtraConfig)
+		rule, err := newHot(rule.ExtraConfig, rule.interval.Clock())
 		if err != nil {
 			logger.Error("hot rule failed", zap.Error(err))
 			return err
@@ -133,7 +153,7 @@ func (rule *extra) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
 	}
 
 	curBet := lot.CurrentBet()
-	if curBet != nil && curBet.Value <= rule.basePrice() {
+	if curBet != nil && curBet.Value <= rule.basePrice() && lot.Reserved(curBet) {
 		curBet.Winner = true
 
 		if err := prx.SaveBet(curBet); err != nil {
@@ -141,7 +161,7 @@ func (rule *extra) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
 			return err
 		}
 
-		n := process.Now()
+		n := rule.now()
 
 		lot.BookedAt = &n
 
@@ -155,7 +175,7 @@ func (rule *extra) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
 			return err
 		}
 
-		r, err := NewConfirm(rule.confirmDuration())
+		r, err := NewConfirm(rule.interval.Clock(), rule.confirmDuration())
 		if err != nil {
 			logger.Error("confirm rule failed", zap.Error(err))
 			return err
@@ -165,8 +185,12 @@ func (rule *extra) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
 			logger.Error("run rule failed", zap.Error(err))
 			return err
 		}
+
+		return nil
 	}
 
+	rule.prolong(prx, rule.LastMoment, rule.ProlongDuration, rule.MaxDuration)
+
 	return nil
 }
 
@@ -188,7 +212,7 @@ func (rule *extra) AcceptBet(act process.AcceptBet, prx process.RuleProxy) error
 		return err
 	}
 
-	n := process.Now()
+	n := rule.now()
 
 	lot.BookedAt = &n
 
@@ -202,7 +226,7 @@ func (rule *extra) AcceptBet(act process.AcceptBet, prx process.RuleProxy) error
 		return err
 	}
 
-	r, err := NewConfirm(rule.confirmDuration())
+	r, err := NewConfirm(rule.interval.Clock(), rule.confirmDuration())
 	if err != nil {
 		logger.Error("confirm rule failed", zap.Error(err))
 		return err
@@ -1,7 +1,10 @@
 package rule
 
 import (
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/process"
+	"time"
 )
 
 const Hot = "hot"
@@ -11,10 +14,13 @@ type hot struct {
 	*ExtraConfig
 }
 
-func newHot(config *ExtraConfig) (*hot, error) {
-	n := now()
+func newHot(config *ExtraConfig, clk clock.Clock) (*hot, error) {
+	return newHotAt(clk.Now(), config, clk)
+}
 
-	interval, err := process.NewInterval(n, config.HotDuration)
+func newHotAt(start time.Time, config *ExtraConfig,
+	clk clock.Clock) (*hot, error) {
+	interval, err := process.NewPeriod(start, config.HotDuration, clk)
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +28,24 @@ func newHot(config *ExtraConfig) (*hot, error) {
 	return &hot{newCommon(Hot, interval, config), config}, nil
 }
 
+func (rule *hot) State() interface{} {
+	return rule.ExtraConfig
+}
+
+func (rule *hot) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
+	if err := rule.common.PlaceBet(act, prx); err != nil {
+		return err
+	}
+
+	rule.prolong(prx, rule.LastMoment, rule.ProlongDuration, rule.MaxDuration)
+
+	return nil
+}
+
 func (rule *hot) CancelBet(act process.Action, prx process.RuleProxy) error {
 	return cancelBetDisabled
 }
+
+func (rule *hot) ProxyBet(prx process.RuleProxy) ([]*core.Bet, error) {
+	return rule.proxyBets(prx)
+}
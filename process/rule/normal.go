@@ -6,14 +6,13 @@ import (
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/process"
 	"time"
-
-	"go.uber.org/zap"
 )
 
 const Normal = "normal"
 
 type NormalConfig struct {
-	BetStep         uint          `json:"bet_step" mapstructure:"bet_step"`
+	BetStep         uint          `json:"bet_step" mapstructure:"bet_step" validate:"gt=0"`
+	BetSteps        BetSteps      `json:"bet_steps" mapstructure:"bet_steps" validate:"dive"`
 	BasePrice       uint          `json:"base_price" validate:"required,gt=0"`
 	LastMoment      time.Duration `mapstructure:"last_moment" validate:"gte=0"`
 	ProlongDuration time.Duration `mapstructure:"prolong_duration" validate:"gt=0"`
@@ -29,8 +28,8 @@ func (conf NormalConfig) price() uint {
 	return conf.BasePrice
 }
 
-func (conf NormalConfig) betStep() uint {
-	return conf.BetStep
+func (conf NormalConfig) betStep(price uint) uint {
+	return conf.BetSteps.step(price, conf.BetStep)
 }
 
 func (conf NormalConfig) confirmDuration() time.Duration {
@@ -47,6 +46,7 @@ type normal struct {
 }
 
 func newNormal(interval process.Interval, defaultConfig NormalConfig) *normal {
+	defaultConfig.BetSteps = defaultConfig.BetSteps.clone()
 	return &normal{newSimple(Normal, interval, &defaultConfig), &defaultConfig}
 }
 
@@ -110,24 +110,15 @@ func (rule *normal) Sync(lot *core.Lot) {
 }
 
 func (rule *normal) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
-	logger := rule.logger.Named("place_bet")
-
 	if err := rule.simple.PlaceBet(act, prx); err != nil {
 		return err
 	}
 
-	rest := prx.End().Sub(now())
-	lastMoment := rule.LastMoment
-	maxTime := rule.interval.Start().Add(rule.MaxDuration)
-	restMaxTime := maxTime.Sub(now())
-
-	if rest < lastMoment && restMaxTime > lastMoment {
-		d := rule.ProlongDuration
-
-		prx.Prolong(d)
-
-		logger.Debug("prolong", zap.Duration("duration", d))
-	}
+	rule.prolong(prx, rule.LastMoment, rule.ProlongDuration, rule.MaxDuration)
 
 	return nil
 }
+
+func (rule *normal) ProxyBet(prx process.RuleProxy) ([]*core.Bet, error) {
+	return rule.proxyBets(prx)
+}
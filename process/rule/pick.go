@@ -66,6 +66,10 @@ func (rule *pick) Config() interface{} {
 	return rule.PickConfig
 }
 
+func (rule *pick) Reservable() bool {
+	return true
+}
+
 func (rule *pick) Sync(lot *core.Lot) {
 	lot.BetStep = rule.BetStep
 	lot.BasePrice = rule.BasePrice
@@ -127,7 +131,7 @@ func (rule *pick) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
 
 	lot.AddBet(newBet)
 
-	n := process.Now()
+	n := rule.now()
 
 	lot.BookedAt = &n
 
@@ -141,7 +145,7 @@ func (rule *pick) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
 		return err
 	}
 
-	r, err := NewConfirm(rule.ConfirmDuration)
+	r, err := NewConfirm(rule.interval.Clock(), rule.ConfirmDuration)
 	if err != nil {
 		logger.Error("confirm rule failed", zap.Error(err))
 		return err
@@ -0,0 +1,194 @@
+package rule
+
+import (
+	"fmt"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/process"
+	"sort"
+	"time"
+
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// NewPreview returns the timeline of the rules during the day of the date
+// in the location, as it is run by the process: the rules and their wait
+// rules are created as by Rules. Unlike Rules it does not stop at the
+// first invalid rule, every rule is checked and the overlapping rules are
+// reported too.
+func NewPreview(configs []*core.RuleConfig, date time.Time,
+	loc *time.Location, defaultConf *Config) *core.RulePreview {
+	y, m, d := date.In(loc).Date()
+	dayStart := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	preview := &core.RulePreview{
+		Date:     dayStart.Format("2006-01-02"),
+		Timeline: []*core.RuleOccurrence{},
+	}
+
+	validate := validator.New()
+
+	clk := clock.NewVirtual(dayStart)
+
+	rules := []process.Rule{}
+	index := map[process.Rule]int{}
+	calendar := false
+
+	for i, conf := range configs {
+		rule, err := newRule(conf, loc, defaultConf, validate, clk)
+		if err != nil {
+			preview.Errors = append(preview.Errors, ruleError(i, err))
+			continue
+		}
+
+		if calendarRule(conf) {
+			calendar = true
+		}
+
+		index[rule] = i
+		rules = append(rules, rule)
+	}
+
+	if len(rules) > 0 {
+		filled, err := fill(rules, calendar, loc, clk)
+		if err != nil {
+			preview.Errors = append(preview.Errors, ruleError(-1, err))
+		} else {
+			rules = filled
+		}
+	}
+
+	for _, rule := range rules {
+		i, ok := index[rule]
+		if !ok {
+			i = -1
+		}
+
+		preview.Timeline = append(preview.Timeline,
+			occurrences(rule, i, dayStart, dayEnd, loc)...)
+	}
+
+	sort.SliceStable(preview.Timeline, func(i, j int) bool {
+		return preview.Timeline[i].Start.Before(preview.Timeline[j].Start)
+	})
+
+	// the time not covered by the occurrences is the time of the wait rule
+	// of the calendar rules, which has no occurrences of its own
+	timeline := make([]*core.RuleOccurrence, 0, 2*len(preview.Timeline)+1)
+
+	covered := dayStart
+	var last *core.RuleOccurrence
+
+	for _, r := range preview.Timeline {
+		if r.Start.After(covered) {
+			timeline = append(timeline, &core.RuleOccurrence{
+				Index: -1,
+				Rule:  Wait,
+				Start: covered,
+				End:   r.Start,
+			})
+		}
+
+		if last != nil && r.Index >= 0 && last.Index >= 0 &&
+			r.Start.Before(last.End) {
+			preview.Errors = append(preview.Errors, &core.RuleError{
+				Index: r.Index,
+				Error: fmt.Sprintf("rule %d overlaps rule %d from %s to %s",
+					r.Index, last.Index, r.Start.Format("15:04:05"),
+					minTime(r.End, last.End).Format("15:04:05")),
+			})
+		}
+
+		timeline = append(timeline, r)
+
+		if last == nil || r.End.After(last.End) {
+			last = r
+		}
+
+		if r.End.After(covered) {
+			covered = r.End
+		}
+	}
+
+	if dayEnd.After(covered) {
+		timeline = append(timeline, &core.RuleOccurrence{
+			Index: -1,
+			Rule:  Wait,
+			Start: covered,
+			End:   dayEnd,
+		})
+	}
+
+	preview.Timeline = timeline
+
+	return preview
+}
+
+// occurrences returns the occurrences of the rule during the day, the
+// occurrence started the day before may last till the day.
+func occurrences(rule process.Rule, index int, dayStart time.Time,
+	dayEnd time.Time, loc *time.Location) []*core.RuleOccurrence {
+	out := []*core.RuleOccurrence{}
+
+	interval := rule.Interval()
+
+	var config interface{}
+	if index >= 0 {
+		config = rule.Config()
+	}
+
+	t := dayStart.Add(-interval.Duration() - time.Nanosecond)
+
+	for {
+		start := interval.Next(t)
+		if start.IsZero() || !start.Before(dayEnd) {
+			break
+		}
+
+		end := start.Add(interval.Duration())
+		if end.After(dayStart) {
+			out = append(out, &core.RuleOccurrence{
+				Index:  index,
+				Rule:   rule.Rule(),
+				Start:  start.In(loc),
+				End:    end.In(loc),
+				Config: config,
+			})
+		}
+
+		t = start
+	}
+
+	return out
+}
+
+// ruleError returns the error of the rule config, the failed validation
+// is reported with its fields and tags.
+func ruleError(index int, err error) *core.RuleError {
+	e := &core.RuleError{Index: index, Error: err.Error()}
+
+	ce, ok := err.(*configError)
+	if !ok {
+		return e
+	}
+
+	e.Error = validationFailed.Error()
+
+	if errs, ok := ce.err.(validator.ValidationErrors); ok {
+		for _, fe := range errs {
+			e.Fields = append(e.Fields, fe.Namespace()+": "+fe.Tag())
+		}
+	} else {
+		e.Fields = []string{ce.err.Error()}
+	}
+
+	return e
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,49 @@
+package rule
+
+import (
+	"encoding/json"
+	"gitlab/nefco/auction/core"
+	"testing"
+	"time"
+)
+
+// TestPreviewWait shows the wait rules created by Rules between the
+// occurrences of the rule.
+func TestPreviewWait(t *testing.T) {
+	configs := []*core.RuleConfig{
+		{
+			Type:     Normal,
+			Start:    "10:00:00",
+			Duration: core.Duration(time.Hour),
+			Props:    json.RawMessage(`{"base_price":10000}`),
+		},
+	}
+
+	preview := NewPreview(configs, at(0, 0), time.UTC, DefaultConfig())
+
+	if len(preview.Errors) != 0 {
+		t.Fatalf("errors = %v", preview.Errors)
+	}
+
+	want := []struct {
+		rule       string
+		start, end time.Time
+	}{
+		{Wait, at(11, 0).AddDate(0, 0, -1), at(10, 0)},
+		{Normal, at(10, 0), at(11, 0)},
+		{Wait, at(11, 0), at(10, 0).AddDate(0, 0, 1)},
+	}
+
+	if len(preview.Timeline) != len(want) {
+		t.Fatalf("timeline = %d occurrences, want %d",
+			len(preview.Timeline), len(want))
+	}
+
+	for i, w := range want {
+		r := preview.Timeline[i]
+		if r.Rule != w.rule || !r.Start.Equal(w.start) || !r.End.Equal(w.end) {
+			t.Errorf("occurrence %d = %s %s-%s, want %s %s-%s", i,
+				r.Rule, r.Start, r.End, w.rule, w.start, w.end)
+		}
+	}
+}
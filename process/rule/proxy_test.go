@@ -0,0 +1,85 @@
+package rule
+
+import (
+	"context"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"testing"
+)
+
+// TestProxyBiddingWar outbids the bet by the proxy bets of two users, which
+// bet against each other step by step until the floor of one of them is
+// reached. Only the last bet of every user is saved.
+func TestProxyBiddingWar(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	for i, floor := range []uint{8000, 8500} {
+		err := prc.PlaceProxy(&testAct{user: testUser(uint(i + 1)),
+			lotID: lot.ID, floor: floor})
+		if err != nil {
+			t.Fatalf("proxy bet %d: %v", floor, err)
+		}
+	}
+
+	if bets := s.lot(lot.ID).Bets; len(bets) != 0 {
+		t.Fatalf("bets = %v, want no bets without the bet to outbid", bets)
+	}
+
+	if err := prc.PlaceBet(&testAct{user: testUser(3), lotID: lot.ID,
+		value: 9500}); err != nil {
+		t.Fatalf("bet: %v", err)
+	}
+
+	final := s.lot(lot.ID)
+
+	values := map[uint]uint{}
+	for _, bet := range final.Bets {
+		values[bet.UserID] = bet.Value
+		if bet.UserID != 3 && !bet.Auto {
+			t.Fatalf("bet %+v is not automatic", bet)
+		}
+	}
+
+	want := map[uint]uint{1: 8000, 2: 8500, 3: 9500}
+	if len(values) != len(want) {
+		t.Fatalf("bets = %v, want %v", values, want)
+	}
+	for user, value := range want {
+		if values[user] != value {
+			t.Fatalf("bets = %v, want %v", values, want)
+		}
+	}
+
+	if bet := final.CurrentBet(); bet.UserID != 1 {
+		t.Fatalf("current bet = %+v, want the bet of the lowest floor", bet)
+	}
+
+	placed := 0
+	for _, action := range s.actions() {
+		if action == core.HistoryBetPlaced {
+			placed++
+		}
+	}
+	if placed != 3 {
+		t.Fatalf("history = %v, want 3 bets placed", s.actions())
+	}
+
+	// the bet below the floors is not outbid
+	if err := prc.CancelProxy(&testAct{user: testUser(2), lotID: lot.ID}); err != nil {
+		t.Fatalf("cancel proxy: %v", err)
+	}
+	if err := prc.PlaceBet(&testAct{user: testUser(2), lotID: lot.ID,
+		value: 7500}); err != nil {
+		t.Fatalf("bet: %v", err)
+	}
+	if bet := s.lot(lot.ID).CurrentBet(); bet.UserID != 2 || bet.Value != 7500 {
+		t.Fatalf("current bet = %+v, want the bet below the floors", bet)
+	}
+}
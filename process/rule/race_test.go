@@ -0,0 +1,113 @@
+package rule
+
+import (
+	"context"
+	"gitlab/nefco/auction/clock"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentCommands places, cancels and prolongs the bets from many
+// goroutines while the timer of the rule fires. It is meant to be run with
+// -race: the process must handle the commands and the timer one by one.
+func TestConcurrentCommands(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	// the bet of the user who never cancels it, so the lot is booked
+	if err := prc.PlaceBet(&testAct{user: testUser(9), lotID: lot.ID,
+		value: 10000}); err != nil {
+		t.Fatalf("place bet: %v", err)
+	}
+
+	// the bets are placed in the last moment, so every bet prolongs the rule
+	clk.Advance(50 * time.Minute)
+
+	expected := map[string]bool{}
+	for _, err := range []error{betStepInvalid, betMustLessCurrentBet,
+		betAlreadyExist, betNotFound, betOtherUser, placeBetDisabled,
+		cancelBetDisabled} {
+		expected[err.Error()] = true
+	}
+	// the bet placed while the lot is booked by the end of the rule
+	expected["Lot already booked"] = true
+
+	var mu sync.Mutex
+	var errs []error
+
+	check := func(err error) {
+		if err != nil && !expected[err.Error()] {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for u := uint(1); u <= 8; u++ {
+		wg.Add(1)
+		go func(u uint) {
+			defer wg.Done()
+			for i := uint(1); i <= 10; i++ {
+				check(prc.PlaceBet(&testAct{user: testUser(u), lotID: lot.ID,
+					value: 10000 - 500*((u+i)%19)}))
+				if i%3 == 0 {
+					check(prc.CancelBet(&testAct{user: testUser(u), lotID: lot.ID}))
+				}
+				_, err := prc.State()
+				check(err)
+			}
+		}(u)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		clk.Advance(3 * time.Hour)
+	}()
+
+	wg.Wait()
+
+	for _, err := range errs {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the process has handled the last fired timer once it waits for the
+	// next one
+	clk.Wait(1)
+
+	st := state(t, prc)
+	final := s.lot(lot.ID)
+
+	winners := 0
+	for _, bet := range final.Bets {
+		if bet.Winner {
+			winners++
+		}
+	}
+
+	// the lot is booked with the bet of the user 9 at least and waits for
+	// the confirmation, or it waits for the next day if the confirmation
+	// ran out or the winner canceled the bet
+	switch st.Rule {
+	case Confirm:
+		if winners != 1 || final.BookedAt == nil {
+			t.Fatalf("lot booked at %v with %d winners", final.BookedAt, winners)
+		}
+	case Wait:
+		if final.BookedAt != nil || len(final.Bets) > 0 {
+			t.Fatalf("lot booked at %v with %d bets", final.BookedAt,
+				len(final.Bets))
+		}
+	default:
+		t.Fatalf("rule = %s, want %s or %s", st.Rule, Confirm, Wait)
+	}
+}
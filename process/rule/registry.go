@@ -0,0 +1,148 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"gitlab/nefco/auction/process"
+	"sort"
+	"sync"
+)
+
+// Factory creates the rule for the interval from the default config of
+// the rule. The config may be shared, the rule must copy it.
+type Factory func(interval process.Interval, config interface{}) process.Rule
+
+// Validator checks the config of the rule after the lot props are applied.
+type Validator func(config interface{}) error
+
+type registration struct {
+	section   func(c *Config) (interface{}, error)
+	factory   Factory
+	validator Validator
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*registration)
+)
+
+// Register adds the rule type to the registry. The config returns a new
+// default config of the rule, for custom rules it is filled from the
+// custom.<name> section of the rule config as JSON, so durations must
+// be core.Duration. If the validator is nil the config is validated by
+// the struct tags.
+func Register(name string, config func() interface{},
+	factory Factory, validator Validator) {
+	register(name, func(c *Config) (interface{}, error) {
+		return c.custom(name, config())
+	}, factory, validator)
+}
+
+func register(name string, section func(c *Config) (interface{}, error),
+	factory Factory, validator Validator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("rule: register factory is nil for " + name)
+	}
+
+	if _, ok := registry[name]; ok {
+		panic("rule: register called twice for " + name)
+	}
+
+	registry[name] = &registration{section, factory, validator}
+}
+
+// Registered returns the names of the registered rule types.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func lookup(name string) (*registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	reg, ok := registry[name]
+	return reg, ok
+}
+
+func init() {
+	register(Normal,
+		func(c *Config) (interface{}, error) { return c.Normal, nil },
+		func(interval process.Interval, config interface{}) process.Rule {
+			return newNormal(interval, *config.(*NormalConfig))
+		}, nil)
+	register(Extra,
+		func(c *Config) (interface{}, error) { return c.Extra, nil },
+		func(interval process.Interval, config interface{}) process.Rule {
+			return newExtra(interval, *config.(*ExtraConfig))
+		}, nil)
+	register(Pick,
+		func(c *Config) (interface{}, error) { return c.Pick, nil },
+		func(interval process.Interval, config interface{}) process.Rule {
+			return newPick(interval, *config.(*PickConfig))
+		}, nil)
+	register(Dutch,
+		func(c *Config) (interface{}, error) { return c.Dutch, nil },
+		func(interval process.Interval, config interface{}) process.Rule {
+			return newDutch(interval, *config.(*DutchConfig))
+		}, nil)
+	register(Sealed,
+		func(c *Config) (interface{}, error) { return c.Sealed, nil },
+		func(interval process.Interval, config interface{}) process.Rule {
+			return newSealed(interval, *config.(*SealedConfig))
+		}, nil)
+}
+
+// custom fills the config of the custom rule from its section.
+func (c *Config) custom(name string, config interface{}) (interface{}, error) {
+	section, ok := c.Custom[name]
+	if !ok {
+		return config, nil
+	}
+
+	data, err := json.Marshal(normalize(section))
+	if err != nil {
+		return nil, fmt.Errorf("config %s marshal error: %s", name, err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("config %s unmarshal error: %s", name, err)
+	}
+
+	return config, nil
+}
+
+// normalize converts the maps decoded from YAML to be marshaled as JSON.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalize(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+		return v
+	}
+	return v
+}
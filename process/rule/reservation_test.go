@@ -0,0 +1,76 @@
+package rule
+
+import (
+	"context"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"testing"
+	"time"
+)
+
+// TestReservationExpiry holds the lot for the user, so that other users
+// can't bet on it or reserve it, until the reservation expires.
+func TestReservationExpiry(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	holder := &testAct{user: testUser(1), lotID: lot.ID}
+	other := &testAct{user: testUser(2), lotID: lot.ID, value: 9000}
+
+	if err := prc.PlaceReservation(holder, 10*time.Minute); err != nil {
+		t.Fatalf("reservation: %v", err)
+	}
+
+	held := s.lot(lot.ID)
+	if held.Holder(clk.Now()) != 1 || !held.ReservedTill.Equal(at(10, 10)) {
+		t.Fatalf("lot reserved by %v till %v, want user 1 till 10:10",
+			held.ReservedBy, held.ReservedTill)
+	}
+
+	if err := prc.PlaceBet(other); err == nil {
+		t.Fatal("bet of other user on the reserved lot accepted")
+	}
+	if err := prc.PlaceReservation(other, time.Minute); err == nil {
+		t.Fatal("reservation of the reserved lot accepted")
+	}
+
+	holder.value = 9500
+	if err := prc.PlaceBet(holder); err != nil {
+		t.Fatalf("bet of the holder: %v", err)
+	}
+
+	// the expiration is handled by the process after the timer fired
+	step(t, clk, prc)
+
+	deadline := time.Now().Add(time.Second)
+	for !expired(s) {
+		if time.Now().After(deadline) {
+			t.Fatalf("history = %v, want %s", s.actions(),
+				core.HistoryReservationExpired)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if free := s.lot(lot.ID); free.ReservedBy != nil {
+		t.Fatalf("lot reserved by %d after the expiration", *free.ReservedBy)
+	}
+
+	if err := prc.PlaceBet(other); err != nil {
+		t.Fatalf("bet after the expiration: %v", err)
+	}
+}
+
+func expired(s *memStore) bool {
+	for _, action := range s.actions() {
+		if action == core.HistoryReservationExpired {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package rule
+
+import (
+	"encoding/json"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/process"
+)
+
+// Restore returns the rule of the saved process state. The rules of the
+// schedule are taken from the rules of the lot, the rules started by other
+// rules are created again with their saved state. It returns nil if the
+// state does not match the rules of the lot anymore.
+func Restore(state *core.ProcessState,
+	rules []process.Rule, clk clock.Clock) (process.Rule, error) {
+	switch state.Rule {
+	case Hot:
+		config := &ExtraConfig{}
+		if err := json.Unmarshal(state.State, config); err != nil {
+			return nil, err
+		}
+		return newHotAt(state.StartAt, config, clk)
+	case Confirm:
+		rule, err := newConfirmAt(state.StartAt,
+			state.EndAt.Sub(state.StartAt), clk)
+		if err != nil {
+			return nil, err
+		}
+		if len(state.State) > 0 {
+			s := &confirmState{}
+			if err := json.Unmarshal(state.State, s); err != nil {
+				return nil, err
+			}
+			rule.offer = s.Offer
+		}
+		return rule, nil
+	}
+
+	if state.RuleIndex < 0 || state.RuleIndex >= len(rules) {
+		return nil, nil
+	}
+
+	rule := rules[state.RuleIndex]
+
+	if rule.Rule() != state.Rule {
+		return nil, nil
+	}
+
+	// the occurrence of the rule is over or the schedule is changed
+	if !rule.Interval().Start().Equal(state.StartAt) {
+		return nil, nil
+	}
+
+	return rule, nil
+}
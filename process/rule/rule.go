@@ -2,6 +2,7 @@ package rule
 
 import (
 	"encoding/json"
+	"gitlab/nefco/auction/clock"
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/errors"
 	"gitlab/nefco/auction/process"
@@ -15,121 +16,171 @@ import (
 
 var (
 	validationFailed = errors.NewError("Validation failed", http.StatusUnprocessableEntity)
+	ruleNotSupported = errors.BadRequest("Rule not supported")
 )
 
 func ValidateRule(validate *validator.Validate) {
 	validate.RegisterValidation("rule",
 		func(fl validator.FieldLevel) bool {
-			rule := fl.Field().String()
-			return rule == Normal || rule == Extra || rule == Pick
+			_, ok := lookup(fl.Field().String())
+			return ok
 		},
 	)
 }
 
-func Rules(configs []*core.RuleConfig, defaultConf *Config) ([]process.Rule, error) {
-	logger := zap.L().Named("rules")
-
+// Rules creates the rules of the lot from their configs. The intervals of
+// the rules are bound to the clock of the process which runs them.
+func Rules(configs []*core.RuleConfig, loc *time.Location,
+	defaultConf *Config, clk clock.Clock) ([]process.Rule, error) {
 	rules := make([]process.Rule, len(configs))
 
 	validate := validator.New()
 
-	for i, conf := range configs {
-		interval, err := process.ParseInterval(conf.Start, conf.Duration.Duration())
-		if err != nil {
-			return nil, err
-		}
-
-		var rule process.Rule
+	calendar := false
 
-		switch conf.Type {
-		case Normal:
-			rule = newNormal(interval, *defaultConf.Normal)
-		case Extra:
-			rule = newExtra(interval, *defaultConf.Extra)
-		case Pick:
-			rule = newPick(interval, *defaultConf.Pick)
+	for i, conf := range configs {
+		rule, err := newRule(conf, loc, defaultConf, validate, clk)
+		if _, ok := err.(*configError); ok {
+			return nil, validationFailed
 		}
-
-		if err := json.Unmarshal(conf.Props, rule); err != nil {
+		if err != nil {
 			return nil, err
 		}
 
-		if err := validate.Struct(rule.Config()); err != nil {
-			logger.Error("validation failed", zap.Error(err))
-			return nil, validationFailed
+		if calendarRule(conf) {
+			calendar = true
 		}
 
 		rules[i] = rule
 	}
 
-	return fillWait(rules)
+	return fill(rules, calendar, loc, clk)
 }
 
-func fillWait(in []process.Rule) ([]process.Rule, error) {
-	sort.Slice(in, func(i, j int) bool {
-		a := in[i].Interval().Start()
-		b := in[j].Interval().Start()
-		return a.Before(b)
-	})
+// configError is the failed validation of the rule config. Rules returns
+// validationFailed instead, the preview reports the failed fields.
+type configError struct {
+	err error
+}
 
-	out := make([]process.Rule, 0, len(in))
+func (e *configError) Error() string {
+	return e.err.Error()
+}
 
-	d := 24 * time.Hour
+func calendarRule(conf *core.RuleConfig) bool {
+	return conf.Date != "" || len(conf.Weekdays) > 0
+}
 
-	fill, err := process.ParseInterval("00:00:00", d)
+// fill adds the wait rules to the rules, so that the process always has
+// the rule to run.
+func fill(rules []process.Rule, calendar bool, loc *time.Location,
+	clk clock.Clock) ([]process.Rule, error) {
+	if calendar {
+		return fillGap(rules, clk), nil
+	}
+	return fillWait(rules, loc, clk)
+}
+
+// newRule creates the rule of the config with the props of the lot
+// applied to the default config of the rule.
+func newRule(conf *core.RuleConfig, loc *time.Location, defaultConf *Config,
+	validate *validator.Validate, clk clock.Clock) (process.Rule, error) {
+	logger := zap.L().Named("rules")
+
+	var interval process.Interval
+	var err error
+
+	if calendarRule(conf) {
+		interval, err = process.ParseSchedule(conf.Start,
+			conf.Duration.Duration(), conf.Date, conf.Weekdays, loc, clk)
+	} else {
+		interval, err = process.ParseInterval(conf.Start,
+			conf.Duration.Duration(), loc, clk)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	for _, r := range in {
-		if r.Interval().Start().Sub(fill.Start()) > 0 {
-			ri, err := process.NewInterval(fill.Start(), r.Interval().Start().Sub(fill.Start()))
-			if err != nil {
-				return nil, err
-			}
+	reg, ok := lookup(conf.Type)
+	if !ok {
+		logger.Warn("rule not supported", zap.String("rule", conf.Type))
+		return nil, ruleNotSupported
+	}
 
-			out = append(out, newWait(ri), r)
+	config, err := reg.section(defaultConf)
+	if err != nil {
+		logger.Error("rule config failed", zap.Error(err))
+		return nil, err
+	}
 
-			d := fill.Duration() - r.Interval().Duration() - ri.Duration()
-			if d < 0 {
-				d = 0
-			}
+	rule := reg.factory(interval, config)
 
-			fill, err = process.NewInterval(r.Interval().End(), d)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			out = append(out, r)
+	if err := json.Unmarshal(conf.Props, rule); err != nil {
+		return nil, err
+	}
 
-			d := fill.Duration() - r.Interval().Duration()
-			if d < 0 {
-				d = 0
-			}
+	if reg.validator != nil {
+		err = reg.validator(rule.Config())
+	} else {
+		err = validate.Struct(rule.Config())
+	}
+	if err != nil {
+		logger.Error("validation failed", zap.Error(err))
+		return nil, &configError{err}
+	}
+
+	return rule, nil
+}
+
+// fillGap adds the wait rule which is active between the occurrences of
+// the calendar rules.
+func fillGap(in []process.Rule, clk clock.Clock) []process.Rule {
+	intervals := make([]process.Interval, len(in))
+	for i, r := range in {
+		intervals[i] = r.Interval()
+	}
+	return append(in, newWait(process.NewGap(clk, intervals...)))
+}
+
+// fillWait adds the wait rules between the daily rules, so that every
+// moment of the day is covered by a rule.
+func fillWait(in []process.Rule, loc *time.Location,
+	clk clock.Clock) ([]process.Rule, error) {
+	offset := func(r process.Rule) time.Duration {
+		return r.Interval().(process.Daily).Offset()
+	}
+
+	sort.Slice(in, func(i, j int) bool {
+		return offset(in[i]) < offset(in[j])
+	})
+
+	out := make([]process.Rule, 0, 2*len(in))
+
+	day := 24 * time.Hour
+
+	var end time.Duration
+
+	for i, r := range in {
+		out = append(out, r)
+
+		if e := offset(r) + r.Interval().Duration(); e > end {
+			end = e
+		}
 
-			fill, err = process.NewInterval(r.Interval().End(), d)
+		next := day + offset(in[0])
+		if i+1 < len(in) {
+			next = offset(in[i+1])
+		}
+
+		if next > end {
+			wi, err := process.NewInterval(end%day, next-end, loc, clk)
 			if err != nil {
 				return nil, err
 			}
-		}
-	}
 
-	if fill.Duration() > 0 {
-		out = append(out, newWait(fill))
+			out = append(out, newWait(wi))
+		}
 	}
 
-	// for _, r := range out {
-	// 	zap.L().Debug("out rule",
-	// 		zap.String("rule", r.Rule()),
-	// 		zap.Time("start", r.Interval().Start()),
-	// 		zap.Time("end", r.Interval().End()),
-	// 		zap.Duration("duration", r.Interval().Duration()),
-	// 	)
-	// }
-
 	return out, nil
 }
-
-func now() time.Time {
-	return time.Now().UTC()
-}
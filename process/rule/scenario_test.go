@@ -0,0 +1,248 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/process"
+	"testing"
+	"time"
+)
+
+func testLot(clk clock.Clock) *core.Lot {
+	return &core.Lot{
+		ID:        1,
+		GroupKey:  "test",
+		ObjectID:  1,
+		CreatedAt: clk.Now(),
+		Rules: core.Rules{
+			{
+				Type:     Normal,
+				Start:    "10:00:00",
+				Duration: core.Duration(time.Hour),
+				Props:    json.RawMessage(`{"base_price":10000}`),
+			},
+		},
+	}
+}
+
+func startProcess(t *testing.T, clk clock.Clock, s *memStore,
+	prcSvc process.ProcessService, lot *core.Lot) process.Process {
+	rules, err := Rules(lot.Rules, lot.Location(), DefaultConfig(), clk)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	tx, _ := s.Begin(lot.ID)
+	defer tx.Commit()
+
+	prc, err := process.New(testUser(0), lot, rules, s, prcSvc, clk, tx, nil, 0)
+	if err != nil {
+		t.Fatalf("new process: %v", err)
+	}
+
+	return prc
+}
+
+// step fires the next timer of the process and waits until the process
+// has handled it and started the timer of the next rule.
+func step(t *testing.T, clk *clock.Virtual, prc process.Process) *core.ProcessState {
+	if _, ok := clk.Step(); !ok {
+		t.Fatal("no timer to fire")
+	}
+	clk.Wait(1)
+	return state(t, prc)
+}
+
+func state(t *testing.T, prc process.Process) *core.ProcessState {
+	state, err := prc.State()
+	if err != nil {
+		t.Fatalf("state: %v", err)
+	}
+	return state
+}
+
+func at(h, m int) time.Time {
+	return time.Date(2024, 3, 4, h, m, 0, 0, time.UTC)
+}
+
+// TestScenarioNoConfirm runs the lot from the wait to the normal rule,
+// prolongs it by the bet in the last moment, books it and runs the
+// confirm rule, which is not confirmed, so the lot waits for the next day.
+func TestScenarioNoConfirm(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	s := newMemStore(clk, lot)
+	prcSvc := &memProcessService{}
+
+	prc := startProcess(t, clk, s, prcSvc, lot)
+	defer prc.Shutdown(context.Background())
+
+	if st := state(t, prc); st.Rule != Wait {
+		t.Fatalf("rule = %s, want %s", st.Rule, Wait)
+	}
+
+	st := step(t, clk, prc)
+	if st.Rule != Normal || !st.StartAt.Equal(at(10, 0)) {
+		t.Fatalf("rule = %s at %s, want %s at 10:00", st.Rule, st.StartAt, Normal)
+	}
+
+	clk.Advance(50 * time.Minute)
+
+	act := &testAct{user: testUser(2), lotID: lot.ID, value: 9500}
+	if err := prc.PlaceBet(act); err != nil {
+		t.Fatalf("place bet: %v", err)
+	}
+
+	st = state(t, prc)
+	if st.Added != 15*time.Minute || !st.EndAt.Equal(at(11, 0)) {
+		t.Fatalf("end = %s + %s, want 11:00 + 15m", st.EndAt, st.Added)
+	}
+
+	st = step(t, clk, prc)
+	if st.Rule != Confirm || !st.StartAt.Equal(at(11, 15)) {
+		t.Fatalf("rule = %s at %s, want %s at 11:15", st.Rule, st.StartAt, Confirm)
+	}
+
+	booked := s.lot(lot.ID)
+	if booked.BookedAt == nil || !booked.BookedAt.Equal(at(11, 15)) {
+		t.Fatalf("booked at = %v, want 11:15", booked.BookedAt)
+	}
+	if bet := booked.CurrentBet(); bet == nil || !bet.Winner || bet.Value != 9500 {
+		t.Fatalf("winner = %+v, want the bet 9500", bet)
+	}
+
+	st = step(t, clk, prc)
+	if st.Rule != Wait {
+		t.Fatalf("rule = %s, want %s", st.Rule, Wait)
+	}
+	if !clk.Now().Equal(at(12, 15)) {
+		t.Fatalf("now = %s, want 12:15", clk.Now())
+	}
+
+	next := s.lot(lot.ID)
+	if next.BookedAt != nil || len(next.Bets) != 0 {
+		t.Fatalf("lot booked at %v with %d bets, want free lot",
+			next.BookedAt, len(next.Bets))
+	}
+
+	actions := s.actions()
+	if n := len(actions); n == 0 || actions[n-1] != core.HistoryLotNoConfirm {
+		t.Fatalf("history = %v, want %s last", actions, core.HistoryLotNoConfirm)
+	}
+
+	st = step(t, clk, prc)
+	if st.Rule != Normal || !st.StartAt.Equal(at(10, 0).AddDate(0, 0, 1)) {
+		t.Fatalf("rule = %s at %s, want %s next day", st.Rule, st.StartAt, Normal)
+	}
+}
+
+// TestProlongMaxDuration prolongs the rule only up to its max duration.
+func TestProlongMaxDuration(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	lot.Rules[0].Props = json.RawMessage(
+		`{"base_price":10000,"max_duration":"1h10m"}`)
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	clk.Advance(50 * time.Minute)
+
+	if err := prc.PlaceBet(&testAct{user: testUser(2), lotID: lot.ID,
+		value: 9500}); err != nil {
+		t.Fatalf("place bet: %v", err)
+	}
+
+	if st := state(t, prc); st.Added != 10*time.Minute {
+		t.Fatalf("added = %s, want 10m", st.Added)
+	}
+
+	clk.Advance(15 * time.Minute)
+
+	if err := prc.PlaceBet(&testAct{user: testUser(3), lotID: lot.ID,
+		value: 9000}); err != nil {
+		t.Fatalf("place bet: %v", err)
+	}
+
+	if st := state(t, prc); st.Added != 10*time.Minute {
+		t.Fatalf("added = %s, want 10m", st.Added)
+	}
+}
+
+// TestRescheduleKeepsBets resumes the rule of the same type with the new
+// end, the bets of the lot are kept.
+func TestRescheduleKeepsBets(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	clk.Advance(10 * time.Minute)
+
+	if err := prc.PlaceBet(&testAct{user: testUser(2), lotID: lot.ID,
+		value: 9500}); err != nil {
+		t.Fatalf("place bet: %v", err)
+	}
+
+	lot.Rules[0].Duration = core.Duration(2 * time.Hour)
+
+	rules, err := Rules(lot.Rules, lot.Location(), DefaultConfig(), clk)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	if err := prc.CheckReschedule(rules, s.lot(lot.ID)); err != nil {
+		t.Fatalf("check reschedule: %v", err)
+	}
+
+	if err := prc.Reschedule(rules); err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+
+	st := state(t, prc)
+	if st.Rule != Normal || !st.EndAt.Equal(at(12, 0)) {
+		t.Fatalf("rule = %s till %s, want %s till 12:00", st.Rule, st.EndAt, Normal)
+	}
+
+	if bets := s.lot(lot.ID).Bets; len(bets) != 1 {
+		t.Fatalf("bets = %d, want 1", len(bets))
+	}
+}
+
+// TestLeaseLost stops the process, when its transaction finds the lease of
+// the lot lost.
+func TestLeaseLost(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := testLot(clk)
+	s := newMemStore(clk, lot)
+	prcSvc := &memProcessService{}
+
+	prc := startProcess(t, clk, s, prcSvc, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	s.loseLease()
+
+	err := prc.PlaceBet(&testAct{user: testUser(2), lotID: lot.ID, value: 9500})
+	if err != core.LotLeaseLost {
+		t.Fatalf("place bet: %v, want %v", err, core.LotLeaseLost)
+	}
+
+	if n := prcSvc.count(); n != 1 {
+		t.Fatalf("stopped = %d, want 1", n)
+	}
+
+	if bets := s.lot(lot.ID).Bets; len(bets) != 0 {
+		t.Fatalf("bets = %d, want 0", len(bets))
+	}
+}
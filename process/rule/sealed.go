@@ -0,0 +1,188 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/process"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const Sealed = "sealed"
+
+type SealedConfig struct {
+	BasePrice       uint          `json:"base_price" validate:"required,gt=0"`
+	SecondPrice     bool          `json:"second_price" mapstructure:"second_price"`
+	ConfirmDuration time.Duration `mapstructure:"confirm_duration" validate:"gt=0"`
+}
+
+func (conf SealedConfig) basePrice() uint {
+	return conf.BasePrice
+}
+
+func (conf SealedConfig) price() uint {
+	return conf.BasePrice
+}
+
+func (conf SealedConfig) betStep(price uint) uint {
+	return 0
+}
+
+func (conf SealedConfig) confirmDuration() time.Duration {
+	return conf.ConfirmDuration
+}
+
+func (conf SealedConfig) extra() bool {
+	return false
+}
+
+type sealed struct {
+	*simple
+	*SealedConfig
+}
+
+func newSealed(interval process.Interval, defaultConfig SealedConfig) *sealed {
+	return &sealed{newSimple(Sealed, interval, &defaultConfig), &defaultConfig}
+}
+
+func (rule *sealed) UnmarshalJSON(data []byte) error {
+	config := struct {
+		*SealedConfig
+		ConfirmDuration core.Duration `json:"confirm_duration"`
+	}{
+		SealedConfig:    rule.SealedConfig,
+		ConfirmDuration: core.Duration(rule.SealedConfig.ConfirmDuration),
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("config sealed duration unmarshal error: %s", err)
+	}
+
+	rule.SealedConfig.ConfirmDuration = time.Duration(config.ConfirmDuration)
+
+	return nil
+}
+
+func (rule sealed) MarshalJSON() ([]byte, error) {
+	config := struct {
+		*SealedConfig
+		ConfirmDuration core.Duration `json:"confirm_duration"`
+	}{
+		SealedConfig:    rule.SealedConfig,
+		ConfirmDuration: core.Duration(rule.SealedConfig.ConfirmDuration),
+	}
+
+	data, err := json.Marshal(&config)
+	if err != nil {
+		return nil, fmt.Errorf("config sealed marshal error: %s", err)
+	}
+
+	return data, nil
+}
+
+func (rule *sealed) Config() interface{} {
+	return rule.SealedConfig
+}
+
+// Sync hides the bets of other users until the end of the interval.
+// Reservable is false, the hold would reveal the bidder of the sealed
+// auction.
+func (rule *sealed) Reservable() bool {
+	return false
+}
+
+func (rule *sealed) Sync(lot *core.Lot) {
+	lot.CurrentPrice = rule.price()
+	lot.Sealed = rule.now().Before(rule.interval.End())
+	rule.simple.Sync(lot)
+}
+
+func (rule *sealed) PlaceBet(act process.PlaceBet, prx process.RuleProxy) error {
+	logger := rule.logger.Named("place_bet")
+
+	lot := prx.ProcessLot()
+
+	if lot.UserBet(act.Executor().ID) != nil {
+		logger.Warn("bet already exist")
+		return betAlreadyExist
+	}
+
+	if act.Value() > rule.price() {
+		logger.Warn("bet invalid")
+		return betInvalid
+	}
+
+	newBet := &core.Bet{
+		Value:  act.Value(),
+		LotID:  lot.ID,
+		UserID: act.Executor().ID,
+	}
+
+	if err := prx.CreateBet(newBet); err != nil {
+		logger.Error("create bet failed", zap.Error(err))
+		return err
+	}
+
+	lot.AddBet(newBet)
+
+	return nil
+}
+
+func (rule *sealed) CancelBet(act process.Action, prx process.RuleProxy) error {
+	logger := rule.logger.Named("cancel_bet")
+
+	lot := prx.ProcessLot()
+
+	userBet := lot.UserBet(act.Executor().ID)
+
+	if userBet == nil {
+		logger.Warn("bet not found")
+		return betNotFound
+	}
+
+	if err := prx.DeleteBet(userBet); err != nil {
+		logger.Error("delete bet failed", zap.Error(err))
+		return err
+	}
+
+	lot.RemoveBet(userBet)
+
+	return nil
+}
+
+// Stop reveals the bets: the lowest bet wins, on equal values the earliest
+// one. In the second price mode the winner is paid the second lowest bet,
+// or the base price if there is no other bet.
+func (rule *sealed) Stop(prx process.RuleProxy) error {
+	lot := prx.ProcessLot()
+
+	bets := make([]*core.Bet, len(lot.Bets))
+	copy(bets, lot.Bets)
+
+	sort.Slice(bets, func(i, j int) bool {
+		if bets[i].Value == bets[j].Value {
+			return bets[i].CreatedAt.Before(bets[j].CreatedAt)
+		}
+		return bets[i].Value < bets[j].Value
+	})
+
+	if len(bets) > 0 {
+		winner := bets[0]
+		winner.Winner = true
+
+		if rule.SecondPrice {
+			settled := rule.BasePrice
+			if len(bets) > 1 {
+				settled = bets[1].Value
+			}
+			winner.Settled = &settled
+		}
+
+		lot.UpdatePrice(lot.UserID)
+	}
+
+	return rule.simple.Stop(prx)
+}
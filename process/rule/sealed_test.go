@@ -0,0 +1,121 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/process"
+	"testing"
+)
+
+func sealedLot(clk clock.Clock, secondPrice bool) *core.Lot {
+	lot := testLot(clk)
+	lot.Rules[0].Type = Sealed
+	lot.Rules[0].Props = json.RawMessage(fmt.Sprintf(
+		`{"base_price":10000,"second_price":%t}`, secondPrice))
+	return lot
+}
+
+// runSealed places the bets of the users 1, 2, ... in the sealed rule and
+// ends it. It returns the lot after the end of the rule.
+func runSealed(t *testing.T, secondPrice bool, values ...uint) *core.Lot {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := sealedLot(clk, secondPrice)
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	placeBets(t, prc, lot.ID, values...)
+
+	if st := step(t, clk, prc); st.Rule != Confirm {
+		t.Fatalf("rule = %s, want %s", st.Rule, Confirm)
+	}
+
+	return s.lot(lot.ID)
+}
+
+func placeBets(t *testing.T, prc process.Process, lotID uint,
+	values ...uint) {
+	for i, value := range values {
+		err := prc.PlaceBet(&testAct{user: testUser(uint(i + 1)),
+			lotID: lotID, value: value})
+		if err != nil {
+			t.Fatalf("bet %d: %v", value, err)
+		}
+	}
+}
+
+func winner(lot *core.Lot) *core.Bet {
+	for _, bet := range lot.Bets {
+		if bet.Winner {
+			return bet
+		}
+	}
+	return nil
+}
+
+// TestSealedHidden checks that the carrier sees only the own bet and the
+// base price until the end of the rule.
+func TestSealedHidden(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+	lot := sealedLot(clk, false)
+	s := newMemStore(clk, lot)
+
+	prc := startProcess(t, clk, s, &memProcessService{}, lot)
+	defer prc.Shutdown(context.Background())
+
+	step(t, clk, prc)
+
+	placeBets(t, prc, lot.ID, 9000, 8000)
+
+	err := prc.PlaceBet(&testAct{user: testUser(1), lotID: lot.ID, value: 7000})
+	if err != betAlreadyExist {
+		t.Fatalf("second bet: got %v, want %v", err, betAlreadyExist)
+	}
+
+	seen := s.lot(lot.ID)
+	prc.Sync(seen)
+	seen.UpdatePrice(1)
+
+	if !seen.Sealed || seen.Bet != nil || seen.CurrentPrice != 10000 ||
+		seen.UserPrice != 9000 {
+		t.Fatalf("lot sealed %t with bet %v at %d, user price %d",
+			seen.Sealed, seen.Bet, seen.CurrentPrice, seen.UserPrice)
+	}
+}
+
+func TestSealedFirstPrice(t *testing.T) {
+	lot := runSealed(t, false, 9000, 8000, 8500)
+
+	bet := winner(lot)
+	if bet == nil || bet.UserID != 2 || bet.Price() != 8000 {
+		t.Fatalf("winner = %+v, want the lowest bet", bet)
+	}
+	if lot.BookedAt == nil {
+		t.Fatal("lot not booked")
+	}
+}
+
+func TestSealedSecondPrice(t *testing.T) {
+	lot := runSealed(t, true, 9000, 8000, 8500)
+
+	bet := winner(lot)
+	if bet == nil || bet.UserID != 2 || bet.Price() != 8500 {
+		t.Fatalf("winner = %+v, want the lowest bet settled at 8500", bet)
+	}
+}
+
+// TestSealedSecondPriceSingle settles the single bet at the base price.
+func TestSealedSecondPriceSingle(t *testing.T) {
+	lot := runSealed(t, true, 9000)
+
+	bet := winner(lot)
+	if bet == nil || bet.UserID != 1 || bet.Price() != 10000 {
+		t.Fatalf("winner = %+v, want the bet settled at the base price", bet)
+	}
+}
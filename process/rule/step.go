@@ -0,0 +1,58 @@
+package rule
+
+import "sort"
+
+// BetStepTier is the bet step applied while the current price is not less
+// than From. The step is a percentage of the current price if the percent
+// is set, otherwise it is fixed.
+type BetStepTier struct {
+	From    uint    `json:"from" mapstructure:"from"`
+	Step    uint    `json:"step" mapstructure:"step"`
+	Percent float64 `json:"percent" mapstructure:"percent" validate:"gte=0,lte=100"`
+}
+
+// BetSteps is the table of the bet steps of the rule.
+type BetSteps []*BetStepTier
+
+// clone copies the table, so that the props of the lot do not change the
+// default config shared by rules.
+func (steps BetSteps) clone() BetSteps {
+	if steps == nil {
+		return nil
+	}
+	tiers := make(BetSteps, len(steps))
+	for i, tier := range steps {
+		t := *tier
+		tiers[i] = &t
+	}
+	return tiers
+}
+
+// step returns the bet step for the current price, the default step is
+// used if no tier matches the price.
+func (steps BetSteps) step(price uint, defaultStep uint) uint {
+	tiers := make(BetSteps, len(steps))
+	copy(tiers, steps)
+
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].From > tiers[j].From
+	})
+
+	for _, tier := range tiers {
+		if price < tier.From {
+			continue
+		}
+		if tier.Percent > 0 {
+			step := uint(float64(price) * tier.Percent / 100)
+			if step == 0 {
+				step = 1
+			}
+			return step
+		}
+		if tier.Step > 0 {
+			return tier.Step
+		}
+	}
+
+	return defaultStep
+}
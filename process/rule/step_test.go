@@ -0,0 +1,59 @@
+package rule
+
+import (
+	"encoding/json"
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"testing"
+	"time"
+)
+
+func TestBetSteps(t *testing.T) {
+	steps := BetSteps{
+		{From: 50000, Percent: 1},
+		{From: 0, Step: 100},
+		{From: 100000},
+	}
+
+	cases := []struct {
+		price, step uint
+	}{
+		{10000, 100},
+		{60000, 600},
+		// the tier without the step falls through to the next one
+		{200000, 2000},
+	}
+
+	for _, c := range cases {
+		if step := steps.step(c.price, 500); step != c.step {
+			t.Errorf("step of %d: got %d, want %d", c.price, step, c.step)
+		}
+	}
+
+	if step := (BetSteps{}).step(10000, 500); step != 500 {
+		t.Errorf("default step: got %d, want 500", step)
+	}
+}
+
+// TestZeroBetStep checks that the lot with the zero bet step is rejected
+// instead of failing on the bet.
+func TestZeroBetStep(t *testing.T) {
+	clk := clock.NewVirtual(at(9, 0))
+
+	for _, typ := range []string{Normal, Extra} {
+		configs := []*core.RuleConfig{
+			{
+				Type:     typ,
+				Start:    "10:00:00",
+				Duration: core.Duration(time.Hour),
+				Props: json.RawMessage(
+					`{"base_price":10000,"extra_price":20000,"bet_step":0}`),
+			},
+		}
+
+		_, err := Rules(configs, time.UTC, DefaultConfig(), clk)
+		if err != validationFailed {
+			t.Errorf("%s: got %v, want validation failed", typ, err)
+		}
+	}
+}
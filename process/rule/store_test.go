@@ -0,0 +1,462 @@
+package rule
+
+import (
+	"gitlab/nefco/auction/clock"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/core/object"
+	"gitlab/nefco/auction/process"
+	"gitlab/nefco/auction/service"
+	"sync"
+	"time"
+)
+
+// memStore is the store of the processes in memory. The transactions are
+// serialized and not rolled back, it is enough to run the rules. The
+// transactions of the process fail after the lease of the lot is lost.
+type memStore struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	lost    bool
+	lots    map[uint]*core.Lot
+	bets    []*core.Bet
+	betID   uint64
+	proxies []*core.ProxyBet
+	history []string
+	states  map[uint]*core.ProcessState
+	booked  int
+}
+
+func newMemStore(clk clock.Clock, lots ...*core.Lot) *memStore {
+	s := &memStore{
+		clock:  clk,
+		lots:   make(map[uint]*core.Lot),
+		states: make(map[uint]*core.ProcessState),
+	}
+	for _, lot := range lots {
+		s.lots[lot.ID] = lot
+	}
+	return s
+}
+
+func (s *memStore) Begin(lotID uint) (process.StoreTx, error) {
+	s.mu.Lock()
+	if s.lost {
+		s.mu.Unlock()
+		return nil, core.LotLeaseLost
+	}
+	return &memTx{s}, nil
+}
+
+func (s *memStore) loseLease() {
+	s.mu.Lock()
+	s.lost = true
+	s.mu.Unlock()
+}
+
+func (s *memStore) BackService(executor *core.User,
+	lot *core.Lot) process.BackService {
+	return &memBackService{s}
+}
+
+// tx runs the function in the transaction of the store.
+func (s *memStore) tx(f func(tx *memTx)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&memTx{s})
+}
+
+func (s *memStore) lot(lotID uint) *core.Lot {
+	var lot *core.Lot
+	s.tx(func(tx *memTx) {
+		lot, _ = tx.Lot(lotID, false)
+	})
+	return lot
+}
+
+func (s *memStore) actions() []string {
+	var actions []string
+	s.tx(func(tx *memTx) {
+		actions = append(actions, s.history...)
+	})
+	return actions
+}
+
+type memTx struct {
+	s *memStore
+}
+
+func (tx *memTx) Commit() error {
+	tx.s.mu.Unlock()
+	return nil
+}
+
+func (tx *memTx) Rollback() error {
+	tx.s.mu.Unlock()
+	return nil
+}
+
+func (tx *memTx) Lots(filter core.LotsFilter) ([]*core.Lot, error) {
+	lots := []*core.Lot{}
+	for id := range tx.s.lots {
+		lot, _ := tx.Lot(id, false)
+		lots = append(lots, lot)
+	}
+	return lots, nil
+}
+
+func (tx *memTx) Lot(id uint, trashed bool) (*core.Lot, error) {
+	saved, ok := tx.s.lots[id]
+	if !ok {
+		return nil, core.LotNotFound
+	}
+	lot := *saved
+	lot.Bets, _ = tx.Bets(id)
+	return &lot, nil
+}
+
+func (tx *memTx) LotByObject(groupKey string, objectID uint) (*core.Lot, error) {
+	return nil, core.LotNotFound
+}
+
+func (tx *memTx) CreateLot(lot *core.Lot) error {
+	saved := *lot
+	tx.s.lots[lot.ID] = &saved
+	return nil
+}
+
+func (tx *memTx) SaveLot(lot *core.Lot) error {
+	saved := *lot
+	saved.Bets = nil
+	tx.s.lots[lot.ID] = &saved
+	return nil
+}
+
+func (tx *memTx) DeleteLot(lot *core.Lot) error {
+	delete(tx.s.lots, lot.ID)
+	return nil
+}
+
+func (tx *memTx) MinBet(lotID uint) (*core.Bet, error) {
+	var min *core.Bet
+	for _, bet := range tx.s.bets {
+		if bet.LotID == lotID && (min == nil || bet.Value < min.Value) {
+			min = bet
+		}
+	}
+	return min, nil
+}
+
+func (tx *memTx) Bets(lotID uint) ([]*core.Bet, error) {
+	bets := []*core.Bet{}
+	for _, bet := range tx.s.bets {
+		if bet.LotID == lotID {
+			b := *bet
+			bets = append(bets, &b)
+		}
+	}
+	return bets, nil
+}
+
+func (tx *memTx) Bet(betID uint) (*core.Bet, error) {
+	for _, bet := range tx.s.bets {
+		if bet.ID == uint64(betID) {
+			b := *bet
+			return &b, nil
+		}
+	}
+	return nil, nil
+}
+
+func (tx *memTx) CreateBet(bet *core.Bet) error {
+	tx.s.betID++
+	bet.ID = tx.s.betID
+	bet.CreatedAt = tx.s.clock.Now()
+	b := *bet
+	tx.s.bets = append(tx.s.bets, &b)
+	return nil
+}
+
+func (tx *memTx) SaveBet(bet *core.Bet) error {
+	for i, b := range tx.s.bets {
+		if b.ID == bet.ID {
+			saved := *bet
+			tx.s.bets[i] = &saved
+		}
+	}
+	return nil
+}
+
+func (tx *memTx) DeleteBet(bet *core.Bet) error {
+	return tx.deleteBets(func(b *core.Bet) bool { return b.ID == bet.ID })
+}
+
+func (tx *memTx) ClearBets(lotID uint) error {
+	return tx.deleteBets(func(b *core.Bet) bool { return b.LotID == lotID })
+}
+
+func (tx *memTx) ClearBetsBefore(lotID uint, t time.Time) error {
+	return tx.deleteBets(func(b *core.Bet) bool {
+		return b.LotID == lotID && !b.CreatedAt.After(t)
+	})
+}
+
+func (tx *memTx) deleteBets(match func(b *core.Bet) bool) error {
+	bets := tx.s.bets[:0]
+	for _, b := range tx.s.bets {
+		if !match(b) {
+			bets = append(bets, b)
+		}
+	}
+	tx.s.bets = bets
+	return nil
+}
+
+func (tx *memTx) ProxyBets(lotID uint) ([]*core.ProxyBet, error) {
+	proxies := []*core.ProxyBet{}
+	for _, p := range tx.s.proxies {
+		if p.LotID == lotID {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies, nil
+}
+
+func (tx *memTx) UserProxyBet(lotID uint, userID uint) (*core.ProxyBet, error) {
+	for _, p := range tx.s.proxies {
+		if p.LotID == lotID && p.UserID == userID {
+			return p, nil
+		}
+	}
+	return nil, service.ProxyBetNotFound
+}
+
+func (tx *memTx) CreateProxyBet(proxyBet *core.ProxyBet) error {
+	proxyBet.CreatedAt = tx.s.clock.Now()
+	tx.s.proxies = append(tx.s.proxies, proxyBet)
+	return nil
+}
+
+func (tx *memTx) DeleteProxyBet(proxyBet *core.ProxyBet) error {
+	proxies := tx.s.proxies[:0]
+	for _, p := range tx.s.proxies {
+		if p != proxyBet {
+			proxies = append(proxies, p)
+		}
+	}
+	tx.s.proxies = proxies
+	return nil
+}
+
+func (tx *memTx) record(action string) error {
+	tx.s.history = append(tx.s.history, action)
+	return nil
+}
+
+func (tx *memTx) LotAdded(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotAdded)
+}
+
+func (tx *memTx) LotUpdated(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotUpdated)
+}
+
+func (tx *memTx) LotDeleted(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotDeleted)
+}
+
+func (tx *memTx) LotBooked(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotBooked)
+}
+
+func (tx *memTx) LotCompleted(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotCompleted)
+}
+
+func (tx *memTx) LotNotWinner(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotNoWinner)
+}
+
+func (tx *memTx) BetPlaced(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryBetPlaced)
+}
+
+func (tx *memTx) ProxyBetPlaced(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryBetPlaced)
+}
+
+func (tx *memTx) BetCanceled(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryBetCanceled)
+}
+
+func (tx *memTx) BookingCanceled(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryBetCanceled)
+}
+
+func (tx *memTx) LotConfirmed(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotConfirmed)
+}
+
+func (tx *memTx) LotConfirmEdited(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotConfirmEdited)
+}
+
+func (tx *memTx) LotNoConfirm(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotNoConfirm)
+}
+
+func (tx *memTx) LotDeleteConfirmation(userID, carrierID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotDeleteConfirmation)
+}
+
+func (tx *memTx) LotBetAccept(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotBetAccept)
+}
+
+func (tx *memTx) LotReplayed(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotReplayed)
+}
+
+func (tx *memTx) LotReserved(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotReserved)
+}
+
+func (tx *memTx) ReservationCanceled(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryReservationCanceled)
+}
+
+func (tx *memTx) ReservationExpired(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryReservationExpired)
+}
+
+func (tx *memTx) LotPublished(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotPublished)
+}
+
+func (tx *memTx) LotExpired(userID uint, lot *core.Lot) error {
+	return tx.record(core.HistoryLotExpired)
+}
+
+func (tx *memTx) History(lotID uint) ([]*core.History, error) {
+	return []*core.History{}, nil
+}
+
+func (tx *memTx) LotEvents(lotID uint) ([]*core.LotEvent, error) {
+	return []*core.LotEvent{}, nil
+}
+
+func (tx *memTx) AppendLotEvent(eventType string, userID uint, lot *core.Lot) error {
+	return nil
+}
+
+func (tx *memTx) ProcessStates() ([]*core.ProcessState, error) {
+	states := []*core.ProcessState{}
+	for _, state := range tx.s.states {
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (tx *memTx) ProcessState(lotID uint) (*core.ProcessState, error) {
+	state, ok := tx.s.states[lotID]
+	if !ok {
+		return nil, core.ProcessStateNotFound
+	}
+	return state, nil
+}
+
+func (tx *memTx) SaveProcessState(state *core.ProcessState) error {
+	tx.s.states[state.LotID] = state
+	return nil
+}
+
+func (tx *memTx) DeleteProcessState(lotID uint) error {
+	delete(tx.s.states, lotID)
+	return nil
+}
+
+type memBackService struct {
+	s *memStore
+}
+
+func (svc *memBackService) PostConfirmation(object.JSONData) error {
+	return nil
+}
+
+func (svc *memBackService) DeleteConfirmation() error {
+	return nil
+}
+
+// SetDateBook is called in the transaction of the process.
+func (svc *memBackService) SetDateBook(time.Time, *core.Bet) error {
+	svc.s.booked++
+	return nil
+}
+
+func (svc *memBackService) ResetDateBook() error {
+	return nil
+}
+
+// memProcessService records the stopped processes.
+type memProcessService struct {
+	mu      sync.Mutex
+	stopped int
+}
+
+func (svc *memProcessService) Stop(prc process.Process) error {
+	svc.mu.Lock()
+	svc.stopped++
+	svc.mu.Unlock()
+	return prc.Stop()
+}
+
+func (svc *memProcessService) count() int {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	return svc.stopped
+}
+
+func (svc *memProcessService) LotChanged(lot *core.Lot) {}
+
+func (svc *memProcessService) LotChangedWithReceiver(lot *core.Lot,
+	receiver *core.User) {
+}
+
+// testAct is the command of the user on the lot.
+type testAct struct {
+	user  *core.User
+	lotID uint
+	value uint
+	floor uint
+	lot   *core.Lot
+}
+
+func (act *testAct) Executor() *core.User {
+	return act.user
+}
+
+func (act *testAct) LotID() uint {
+	return act.lotID
+}
+
+func (act *testAct) GetLot() *core.Lot {
+	return act.lot
+}
+
+func (act *testAct) SetLot(lot *core.Lot) {
+	act.lot = lot
+}
+
+func (act *testAct) Value() uint {
+	return act.value
+}
+
+func (act *testAct) Floor() uint {
+	return act.floor
+}
+
+func testUser(id uint) *core.User {
+	return &core.User{
+		ID:     id,
+		Groups: []*core.Group{{Key: "test"}},
+	}
+}
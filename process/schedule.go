@@ -0,0 +1,226 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"gitlab/nefco/auction/clock"
+	"strings"
+	"time"
+)
+
+// lookahead is the time by which the next rule is chosen in advance.
+const lookahead = 1 * time.Second
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// schedule is an interval started once on the given date or repeated on
+// the given weekdays. Unlike the daily interval the duration of a schedule
+// may be longer than 24 hours.
+type schedule struct {
+	offset   time.Duration
+	duration time.Duration
+	date     time.Time
+	weekdays map[time.Weekday]bool
+	loc      *time.Location
+	clock    clock.Clock
+}
+
+func ParseSchedule(startTime string, duration time.Duration,
+	date string, days []string, loc *time.Location,
+	clk clock.Clock) (*schedule, error) {
+	start, err := time.Parse("15:04:05", startTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if duration < 0 {
+		err := errors.New(`the beginning of the period should be
+			earlier than the end of the period`)
+		return nil, err
+	}
+
+	if date != "" && len(days) > 0 {
+		err := errors.New(`the date and the weekdays of the rule
+			can not be set together`)
+		return nil, err
+	}
+
+	s := &schedule{
+		offset:   timeOfDay(start),
+		duration: duration,
+		weekdays: make(map[time.Weekday]bool),
+		loc:      loc,
+		clock:    clk,
+	}
+
+	if date != "" {
+		s.date, err = time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, day := range days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", day)
+		}
+		s.weekdays[weekday] = true
+	}
+
+	return s, nil
+}
+
+// Start returns the start of the current occurrence, the last one if
+// it has already ended or the next one if there was none yet.
+func (s schedule) Start() time.Time {
+	t := s.clock.Now().Add(lookahead)
+	if start, ok := s.prev(t); ok {
+		return start
+	}
+	start, _ := s.next(t)
+	return start
+}
+
+func (s schedule) End() time.Time {
+	return s.end(s.Start())
+}
+
+func (s schedule) Duration() time.Duration {
+	return s.duration
+}
+
+func (s schedule) Rest(t time.Time) time.Duration {
+	return s.End().Sub(t)
+}
+
+func (s schedule) Contains(t time.Time) bool {
+	start, ok := s.prev(t)
+	return ok && t.After(start) && t.Before(s.end(start))
+}
+
+func (s schedule) Next(t time.Time) time.Time {
+	if start, ok := s.next(t); ok {
+		return start
+	}
+	return time.Time{}
+}
+
+func (s schedule) Clock() clock.Clock {
+	return s.clock
+}
+
+func (s schedule) end(start time.Time) time.Time {
+	return wallclock(start.In(s.loc), s.offset+s.duration, s.loc)
+}
+
+// day returns the start of the occurrence on the day d.
+func (s schedule) day(d time.Time) (time.Time, bool) {
+	if len(s.weekdays) > 0 && !s.weekdays[d.Weekday()] {
+		return time.Time{}, false
+	}
+	return wallclock(d, s.offset, s.loc), true
+}
+
+// prev returns the start of the last occurrence started before t.
+func (s schedule) prev(t time.Time) (time.Time, bool) {
+	if !s.date.IsZero() {
+		start := wallclock(s.date, s.offset, s.loc)
+		return start, !start.After(t)
+	}
+	day := t.In(s.loc)
+	for i := 0; i <= 7; i++ {
+		start, ok := s.day(day.AddDate(0, 0, -i))
+		if ok && !start.After(t) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// next returns the start of the first occurrence started after t.
+func (s schedule) next(t time.Time) (time.Time, bool) {
+	if !s.date.IsZero() {
+		start := wallclock(s.date, s.offset, s.loc)
+		return start, start.After(t)
+	}
+	day := t.In(s.loc)
+	for i := 0; i <= 8; i++ {
+		start, ok := s.day(day.AddDate(0, 0, i))
+		if ok && start.After(t) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// gap is the interval between the occurrences of other intervals. It lasts
+// until the next occurrence but not longer than a day.
+type gap struct {
+	intervals []Interval
+	clock     clock.Clock
+}
+
+func NewGap(clk clock.Clock, intervals ...Interval) *gap {
+	return &gap{intervals, clk}
+}
+
+func (g gap) Start() time.Time {
+	n := g.clock.Now()
+	start := time.Time{}
+	for _, i := range g.intervals {
+		end := i.End()
+		if !end.After(n) && end.After(start) {
+			start = end
+		}
+	}
+	if start.IsZero() {
+		return n
+	}
+	return start
+}
+
+func (g gap) End() time.Time {
+	n := g.clock.Now()
+	end := n.Add(24 * time.Hour)
+	for _, i := range g.intervals {
+		next := i.Next(n)
+		if !next.IsZero() && next.Before(end) {
+			end = next
+		}
+	}
+	return end
+}
+
+func (g gap) Duration() time.Duration {
+	return g.End().Sub(g.Start())
+}
+
+func (g gap) Rest(t time.Time) time.Duration {
+	return g.End().Sub(t)
+}
+
+func (g gap) Contains(t time.Time) bool {
+	for _, i := range g.intervals {
+		if i.Contains(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g gap) Next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+func (g gap) Clock() clock.Clock {
+	return g.clock
+}
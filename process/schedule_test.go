@@ -0,0 +1,91 @@
+package process
+
+import (
+	"gitlab/nefco/auction/clock"
+	"testing"
+	"time"
+)
+
+func berlin(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone: %v", err)
+	}
+	return loc
+}
+
+// TestScheduleWeekdays runs the saturday schedule over the change to the
+// summer time: the end keeps the wall clock, so the occurrence is an hour
+// shorter.
+func TestScheduleWeekdays(t *testing.T) {
+	loc := berlin(t)
+	now := time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewVirtual(now)
+
+	s, err := ParseSchedule("22:00:00", 30*time.Hour, "",
+		[]string{"Sat"}, loc, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 3, 30, 21, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC)
+	next := time.Date(2024, 4, 6, 20, 0, 0, 0, time.UTC)
+
+	if !s.Start().Equal(start) || !s.End().Equal(end) {
+		t.Fatalf("occurrence %v - %v, want %v - %v",
+			s.Start(), s.End(), start, end)
+	}
+	if !s.Contains(now) || s.Contains(end.Add(time.Minute)) {
+		t.Fatalf("contains %v: %t, %v: %t", now, s.Contains(now),
+			end.Add(time.Minute), s.Contains(end.Add(time.Minute)))
+	}
+	if n := s.Next(now); !n.Equal(next) {
+		t.Fatalf("next = %v, want %v", n, next)
+	}
+}
+
+// TestScheduleDate runs the schedule on the date of the change to the
+// winter time: the occurrence is an hour longer and is not repeated.
+func TestScheduleDate(t *testing.T) {
+	loc := berlin(t)
+	now := time.Date(2024, 10, 26, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewVirtual(now)
+
+	s, err := ParseSchedule("01:00:00", 3*time.Hour, "2024-10-27",
+		nil, loc, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 10, 26, 23, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 10, 27, 3, 0, 0, 0, time.UTC)
+
+	if !s.Start().Equal(start) || !s.End().Equal(end) {
+		t.Fatalf("occurrence %v - %v, want %v - %v",
+			s.Start(), s.End(), start, end)
+	}
+	if n := s.Next(now); !n.Equal(start) {
+		t.Fatalf("next = %v, want %v", n, start)
+	}
+
+	clk.Advance(14 * time.Hour)
+
+	if !s.Contains(clk.Now()) {
+		t.Fatalf("schedule does not contain %v", clk.Now())
+	}
+	if n := s.Next(clk.Now()); !n.IsZero() {
+		t.Fatalf("next = %v, want no next occurrence", n)
+	}
+
+	clk.Advance(24 * time.Hour)
+
+	if !s.Start().Equal(start) || s.Contains(clk.Now()) {
+		t.Fatalf("start = %v, want the past occurrence", s.Start())
+	}
+
+	if _, err := ParseSchedule("01:00:00", time.Hour, "2024-10-27",
+		[]string{"sun"}, loc, clk); err == nil {
+		t.Fatal("schedule with the date and the weekdays accepted")
+	}
+}
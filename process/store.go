@@ -0,0 +1,106 @@
+package process
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+	"gitlab/nefco/auction/service"
+)
+
+// Tx is the transaction of the process handler with the services bound to
+// it.
+type Tx interface {
+	core.LotService
+	core.BetService
+	core.ProxyBetService
+	core.HistoryService
+	core.LotEventService
+	core.ProcessStateService
+}
+
+// StoreTx is the transaction begun by the process itself.
+type StoreTx interface {
+	Tx
+	Commit() error
+	Rollback() error
+}
+
+// Store begins the transactions of the processes and creates the back
+// service notified about the lot. The processes of the auction use the
+// database, the simulations of the processes may use their own store.
+//
+// Begin returns core.LotLeaseLost if the process of the lot may no longer
+// change it, the process is stopped then.
+type Store interface {
+	Begin(lotID uint) (StoreTx, error)
+	BackService(executor *core.User, lot *core.Lot) BackService
+}
+
+type dbTx struct {
+	core.LotService
+	core.BetService
+	core.ProxyBetService
+	core.HistoryService
+	core.LotEventService
+	core.ProcessStateService
+}
+
+// NewTx returns the services of the database transaction.
+func NewTx(tx *db.Tx) Tx {
+	return &dbTx{
+		LotService:          service.NewLotService(tx),
+		BetService:          service.NewBetService(tx),
+		ProxyBetService:     service.NewProxyBetService(tx),
+		HistoryService:      service.NewHistoryService(tx),
+		LotEventService:     service.NewLotEventService(tx),
+		ProcessStateService: service.NewProcessStateService(tx),
+	}
+}
+
+type dbStoreTx struct {
+	Tx
+	tx *db.Tx
+}
+
+func (tx *dbStoreTx) Commit() error {
+	return tx.tx.Commit()
+}
+
+func (tx *dbStoreTx) Rollback() error {
+	return tx.tx.Rollback()
+}
+
+// Fence checks in the transaction that the process of the lot may change
+// it, e.g. that the instance still holds the lease of the lot.
+type Fence func(tx *db.Tx, lotID uint) error
+
+type dbStore struct {
+	db             *db.DB
+	cfgBackService *service.ConfigBackService
+	fence          Fence
+}
+
+func NewStore(database *db.DB,
+	cfgBackService *service.ConfigBackService, fence Fence) Store {
+	return &dbStore{database, cfgBackService, fence}
+}
+
+func (s *dbStore) Begin(lotID uint) (StoreTx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.fence != nil {
+		if err := s.fence(tx, lotID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return &dbStoreTx{NewTx(tx), tx}, nil
+}
+
+func (s *dbStore) BackService(executor *core.User,
+	lot *core.Lot) BackService {
+	return service.NewBackService(s.cfgBackService, executor, lot)
+}
@@ -2,6 +2,8 @@ package process
 
 import (
 	"errors"
+	"gitlab/nefco/auction/clock"
+	"sync"
 	"time"
 )
 
@@ -9,78 +11,134 @@ type Timeline interface {
 	Start() time.Time
 	End() time.Time
 	Added() time.Duration
-	Run(start time.Time, end time.Time) <-chan bool
+	Run(start time.Time, end time.Time, added time.Duration, complete func())
 	Stop() error
 	Prolong(d time.Duration)
 }
 
+// timeline is owned by the process goroutine: Run, Stop and Prolong are
+// called and the complete handler is run only there. The timer goroutine
+// posts the end of the timeline to the mailbox of the process, the end of
+// a stopped or restarted timer is ignored by its generation. The start,
+// the end and the added time are guarded by the mutex, because they are
+// read when the lot is synced by other goroutines.
 type timeline struct {
-	start      time.Time
-	end        time.Time
-	added      time.Duration
-	isRun      bool
-	chComplete chan bool
-	chSkip     chan bool
-	chStop     chan bool
+	mu       sync.RWMutex
+	start    time.Time
+	end      time.Time
+	added    time.Duration
+	isRun    bool
+	gen      uint64
+	cancel   chan struct{}
+	timer    clock.Timer
+	complete func()
+	clock    clock.Clock
+	post     func(handler func())
 }
 
-func newTimeline() *timeline {
-	return &timeline{
-		chComplete: make(chan bool),
-		chSkip:     make(chan bool),
-		chStop:     make(chan bool),
-	}
+func newTimeline(clk clock.Clock, post func(handler func())) *timeline {
+	return &timeline{clock: clk, post: post}
 }
 
 func (t *timeline) Start() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return t.start
 }
 
 func (t *timeline) End() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return t.end.Add(t.added)
 }
 
 func (t *timeline) Added() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return t.added
 }
 
-func (t *timeline) Run(start time.Time, end time.Time) <-chan bool {
-	if t.isRun {
-		t.chStop <- true
-	}
+// Run starts the timer till the end prolonged by added, the running timer
+// is stopped. The added time is not zero when the prolonged rule is
+// restored. The complete handler is called when the timer ends, but not
+// when it is stopped.
+func (t *timeline) Run(start time.Time, end time.Time,
+	added time.Duration, complete func()) {
+	t.mu.Lock()
 	t.start = start
 	t.end = end
-	t.added = 0
+	t.added = added
+	t.mu.Unlock()
+
 	t.isRun = true
-	rest := end.Sub(start)
-	go t.runTimer(rest)
-	return t.chComplete
+	t.complete = complete
+	t.schedule(end.Add(added).Sub(start))
 }
 
 func (t *timeline) Prolong(d time.Duration) {
-	t.chSkip <- true
+	if !t.isRun {
+		return
+	}
+
+	t.mu.Lock()
 	t.added += d
-	rest := t.end.Add(t.added).Sub(now())
-	go t.runTimer(rest)
+	rest := t.end.Add(t.added).Sub(t.clock.Now())
+	t.mu.Unlock()
+
+	t.schedule(rest)
 }
 
 func (t *timeline) Stop() error {
 	if !t.isRun {
 		return errors.New("timer not started")
 	}
-	t.chStop <- true
+	t.isRun = false
+	t.stopTimer()
 	return nil
 }
 
-func (t *timeline) runTimer(d time.Duration) {
-	timer := time.NewTimer(d)
-	select {
-	case <-t.chSkip:
-	case <-t.chStop:
-		t.isRun = false
-		t.chComplete <- false
-	case <-timer.C:
-		t.isRun = false
-		t.chComplete <- true
+// schedule replaces the timer with the new one. The replaced timer is
+// stopped at once, so it does not fire in the virtual time.
+func (t *timeline) schedule(d time.Duration) {
+	t.stopTimer()
+
+	t.gen++
+	gen := t.gen
+
+	cancel := make(chan struct{})
+	t.cancel = cancel
+
+	timer := t.clock.NewTimer(d)
+	t.timer = timer
+
+	go func() {
+		select {
+		case <-timer.C():
+			t.post(func() {
+				t.fire(gen)
+			})
+		case <-cancel:
+		}
+	}()
+}
+
+func (t *timeline) fire(gen uint64) {
+	if gen != t.gen || !t.isRun {
+		return
+	}
+	t.isRun = false
+	t.cancel = nil
+	t.timer = nil
+	t.complete()
+}
+
+func (t *timeline) stopTimer() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if t.cancel != nil {
+		close(t.cancel)
+		t.cancel = nil
 	}
 }
@@ -0,0 +1,259 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+const CommandAddLotsBulk = "add.lots.bulk"
+
+const maxBulkRows = 1000
+
+const (
+	BulkCreated = "created"
+	BulkExists  = "exists"
+	BulkInvalid = "invalid"
+	BulkFailed  = "failed"
+)
+
+var (
+	bulkEmpty       = errors.BadRequest("Bulk lots empty")
+	bulkTooManyRows = errors.BadRequest("Bulk lots too many rows")
+)
+
+// bulkStringColumns are the CSV columns kept as strings, the other columns
+// are decoded as JSON when possible, so rules and object are JSON cells.
+var bulkStringColumns = map[string]bool{
+	"group_key":  true,
+	"time_zone":  true,
+	"booked_at":  true,
+	"publish_at": true,
+	"expires_at": true,
+}
+
+// BulkResult is the result of a row, rows are numbered from 1 without the
+// header of CSV.
+type BulkResult struct {
+	Row    int      `json:"row"`
+	Status string   `json:"status"`
+	LotID  uint     `json:"lot_id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type AddLotsBulk struct {
+	*base
+	lots    []*core.Lot
+	results []*BulkResult
+}
+
+func newAddLotsBulk(user *core.User) *AddLotsBulk {
+	return &AddLotsBulk{
+		base: newBase(CommandAddLotsBulk, AccessManager, user),
+	}
+}
+
+// GetLots returns the lots of the rows, nil for the invalid rows.
+func (cmd *AddLotsBulk) GetLots() []*core.Lot {
+	return cmd.lots
+}
+
+func (cmd *AddLotsBulk) LotCreated(i int, lot *core.Lot) {
+	cmd.results[i].Status = BulkCreated
+	cmd.results[i].LotID = lot.ID
+}
+
+func (cmd *AddLotsBulk) LotExists(i int) {
+	cmd.results[i].Status = BulkExists
+}
+
+func (cmd *AddLotsBulk) LotFailed(i int, err error) {
+	cmd.results[i].Status = BulkFailed
+	cmd.results[i].Errors = []string{err.Error()}
+}
+
+// Validate validates every row separately, so an invalid row does not
+// fail the whole command.
+func (cmd *AddLotsBulk) Validate(validate *validator.Validate) {
+	for i, lot := range cmd.lots {
+		if lot == nil {
+			continue
+		}
+
+		err := validate.Struct(lot)
+		if err == nil {
+			continue
+		}
+
+		cmd.lots[i] = nil
+		cmd.results[i].Status = BulkInvalid
+
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			for _, e := range errs {
+				cmd.results[i].Errors = append(cmd.results[i].Errors,
+					e.Namespace()+": "+e.Tag())
+			}
+		} else {
+			cmd.results[i].Errors = []string{err.Error()}
+		}
+	}
+}
+
+func (cmd *AddLotsBulk) Event() Event {
+	e := eventAddLotsBulk(cmd.results)
+	return &e
+}
+
+// UnmarshalJSON reads the lots of the WS command, the payload is an array
+// of lots.
+func (cmd *AddLotsBulk) UnmarshalJSON(b []byte) error {
+	rows := []json.RawMessage{}
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return err
+	}
+	return cmd.fill(rows)
+}
+
+// eject reads CSV with the header row if the content type is text/csv,
+// a JSON array of lots or JSON lines otherwise.
+func (cmd *AddLotsBulk) eject(ctx echo.Context) error {
+	body, err := ioutil.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	contentType := ctx.Request().Header.Get(echo.HeaderContentType)
+
+	if strings.Contains(contentType, "csv") {
+		rows, err := csvRows(body)
+		if err != nil {
+			return err
+		}
+		return cmd.fill(rows)
+	}
+
+	body = bytes.TrimSpace(body)
+
+	if bytes.HasPrefix(body, []byte("[")) {
+		return cmd.UnmarshalJSON(body)
+	}
+
+	rows := []json.RawMessage{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, 1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rows = append(rows, json.RawMessage(append([]byte{}, line...)))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return cmd.fill(rows)
+}
+
+func (cmd *AddLotsBulk) fill(rows []json.RawMessage) error {
+	if len(rows) == 0 {
+		return bulkEmpty
+	}
+
+	if len(rows) > maxBulkRows {
+		return bulkTooManyRows
+	}
+
+	cmd.lots = make([]*core.Lot, len(rows))
+	cmd.results = make([]*BulkResult, len(rows))
+
+	for i, row := range rows {
+		cmd.results[i] = &BulkResult{Row: i + 1}
+
+		lot := &core.Lot{}
+
+		if err := json.Unmarshal(row, lot); err != nil {
+			cmd.results[i].Status = BulkInvalid
+			cmd.results[i].Errors = []string{err.Error()}
+			continue
+		}
+
+		cmd.lots[i] = lot
+	}
+
+	return nil
+}
+
+// csvRows converts the CSV rows to JSON objects by the header row.
+func csvRows(body []byte) ([]json.RawMessage, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []json.RawMessage{}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(header))
+
+		for i, column := range header {
+			if i >= len(record) || len(record[i]) == 0 {
+				continue
+			}
+
+			column = strings.TrimSpace(column)
+
+			var value interface{}
+
+			if bulkStringColumns[column] ||
+				json.Unmarshal([]byte(record[i]), &value) != nil {
+				value = record[i]
+			}
+
+			row[column] = value
+		}
+
+		b, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, b)
+	}
+
+	return rows, nil
+}
+
+type eventAddLotsBulk []*BulkResult
+
+func (evt *eventAddLotsBulk) Event() string {
+	return successName(CommandAddLotsBulk)
+}
+
+func (evt *eventAddLotsBulk) Code() int {
+	return http.StatusOK
+}
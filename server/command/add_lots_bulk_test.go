@@ -0,0 +1,109 @@
+package command
+
+import (
+	"errors"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/core/object"
+	"strings"
+	"testing"
+
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+const bulkObject = `{"type":"trip","data":{` +
+	`"orders":[{"consignee_name":"ООО Ромашка","consignee_address":"Москва"}],` +
+	`"points":[` +
+	`{"unloading":true,"address":"Москва","date":"2024-03-04T10:00:00Z"},` +
+	`{"unloading":true,"address":"Тверь","date":"2024-03-05T10:00:00Z"}],` +
+	`"tonnage":20,"docs_pack_url":"https://example.com/docs"}}`
+
+const bulkRules = `[{"type":"normal","start":"10:00:00","duration":"1h",` +
+	`"props":{"base_price":10000}}]`
+
+func bulkRow(objectID, obj string) string {
+	return strings.Join([]string{"test", objectID,
+		`"` + strings.Replace(obj, `"`, `""`, -1) + `"`,
+		`"` + strings.Replace(bulkRules, `"`, `""`, -1) + `"`}, ",")
+}
+
+// TestAddLotsBulkPartial keeps the results of the valid rows, the invalid
+// rows are reported by their numbers and do not fail the command.
+func TestAddLotsBulkPartial(t *testing.T) {
+	csv := strings.Join([]string{
+		"group_key,object_id,object,rules",
+		bulkRow("1", bulkObject),
+		bulkRow("", bulkObject),
+		bulkRow("3", `{"type":"trip","data":`),
+		bulkRow("4", bulkObject),
+		bulkRow("5", bulkObject),
+	}, "\n")
+
+	rows, err := csvRows([]byte(csv))
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+
+	cmd := newAddLotsBulk(&core.User{ID: 1})
+	if err := cmd.fill(rows); err != nil {
+		t.Fatalf("fill: %v", err)
+	}
+
+	validate := validator.New()
+	core.ValidateState(validate)
+	core.ValidateTimeZone(validate)
+	object.ValidateObjectType(validate)
+	// the rules are registered by the process, which imports the commands
+	validate.RegisterValidation("rule", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() == "normal"
+	})
+
+	cmd.Validate(validate)
+
+	lots := cmd.GetLots()
+	for i, valid := range []bool{true, false, false, true, true} {
+		if (lots[i] != nil) != valid {
+			t.Fatalf("row %d: lot %v, valid %t, results %+v", i+1, lots[i],
+				valid, cmd.results[i])
+		}
+	}
+
+	// the lots left are created by the auction one by one
+	cmd.LotCreated(0, &core.Lot{ID: 7})
+	cmd.LotExists(3)
+	cmd.LotFailed(4, errors.New("Group not found"))
+
+	results := *cmd.Event().(*eventAddLotsBulk)
+
+	want := []string{BulkCreated, BulkInvalid, BulkInvalid, BulkExists, BulkFailed}
+	if len(results) != len(want) {
+		t.Fatalf("results = %d, want %d", len(results), len(want))
+	}
+	for i, status := range want {
+		if r := results[i]; r.Row != i+1 || r.Status != status {
+			t.Errorf("result %+v, want row %d %s", r, i+1, status)
+		}
+	}
+
+	if results[0].LotID != 7 {
+		t.Errorf("lot id = %d, want 7", results[0].LotID)
+	}
+	if errs := results[1].Errors; len(errs) != 1 || !strings.Contains(errs[0], "ObjectID") {
+		t.Errorf("errors = %v, want the object id required", errs)
+	}
+	if len(results[2].Errors) == 0 || len(results[4].Errors) == 0 {
+		t.Errorf("results %+v, %+v without errors", results[2], results[4])
+	}
+}
+
+func TestAddLotsBulkLimits(t *testing.T) {
+	cmd := newAddLotsBulk(&core.User{ID: 1})
+
+	if err := cmd.UnmarshalJSON([]byte(`[]`)); err != bulkEmpty {
+		t.Errorf("empty: got %v, want %v", err, bulkEmpty)
+	}
+
+	rows := "[" + strings.Repeat("{},", maxBulkRows) + "{}]"
+	if err := cmd.UnmarshalJSON([]byte(rows)); err != bulkTooManyRows {
+		t.Errorf("too many rows: got %v, want %v", err, bulkTooManyRows)
+	}
+}
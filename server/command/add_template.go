@@ -0,0 +1,40 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+const CommandAddTemplate = "add.template"
+
+type AddTemplate struct {
+	*base
+	*core.LotTemplate
+}
+
+func newAddTemplate(user *core.User) *AddTemplate {
+	return &AddTemplate{
+		base:        newBase(CommandAddTemplate, AccessManager, user),
+		LotTemplate: &core.LotTemplate{},
+	}
+}
+
+func (cmd *AddTemplate) GetTemplate() *core.LotTemplate {
+	return cmd.LotTemplate
+}
+
+func (cmd *AddTemplate) Event() Event {
+	return &struct {
+		*event
+		*core.LotTemplate
+	}{
+		newSucces(cmd.name, http.StatusCreated),
+		cmd.LotTemplate,
+	}
+}
+
+func (cmd *AddTemplate) eject(ctx echo.Context) error {
+	return ctx.Bind(cmd)
+}
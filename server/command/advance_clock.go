@@ -0,0 +1,52 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const CommandAdvanceClock = "advance.clock"
+
+// AdvanceClock moves the virtual clock of the auction by the duration or
+// to the next timer if the step is set.
+type AdvanceClock struct {
+	*base
+	Duration core.Duration `json:"duration"`
+	Step     bool          `json:"step"`
+	now      time.Time
+}
+
+func newAdvanceClock(user *core.User) *AdvanceClock {
+	return &AdvanceClock{
+		base: newBase(CommandAdvanceClock, AccessRoot, user),
+	}
+}
+
+func (cmd *AdvanceClock) GetDuration() time.Duration {
+	return time.Duration(cmd.Duration)
+}
+
+func (cmd *AdvanceClock) NextTimer() bool {
+	return cmd.Step
+}
+
+func (cmd *AdvanceClock) SetNow(now time.Time) {
+	cmd.now = now
+}
+
+func (cmd *AdvanceClock) Event() Event {
+	return &struct {
+		*event
+		Now time.Time `json:"now"`
+	}{
+		newSucces(cmd.name, http.StatusOK),
+		cmd.now,
+	}
+}
+
+func (cmd *AdvanceClock) eject(ctx echo.Context) error {
+	return ctx.Bind(cmd)
+}
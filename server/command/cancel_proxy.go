@@ -0,0 +1,24 @@
+package command
+
+import "gitlab/nefco/auction/core"
+
+const CommandCancelProxy = "cancel.proxy"
+
+type CancelProxy struct {
+	*lot
+	l *core.Lot
+}
+
+func newCancelProxy(user *core.User) *CancelProxy {
+	return &CancelProxy{
+		lot: newLot(CommandCancelProxy, AccessUser, user),
+	}
+}
+
+func (cmd *CancelProxy) GetLot() *core.Lot {
+	return cmd.l
+}
+
+func (cmd *CancelProxy) SetLot(lot *core.Lot) {
+	cmd.l = lot
+}
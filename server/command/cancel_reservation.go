@@ -6,6 +6,7 @@ const CommandCancelReservation = "cancel.reservation"
 
 type CancelReservation struct {
 	*lot
+	l *core.Lot
 }
 
 func newCancelReservation(user *core.User) *CancelReservation {
@@ -13,3 +14,11 @@ func newCancelReservation(user *core.User) *CancelReservation {
 		lot: newLot(CommandCancelReservation, AccessUser, user),
 	}
 }
+
+func (cmd *CancelReservation) GetLot() *core.Lot {
+	return cmd.l
+}
+
+func (cmd *CancelReservation) SetLot(lot *core.Lot) {
+	cmd.l = lot
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"encoding/json"
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/errors"
 	"net/http"
@@ -16,12 +17,15 @@ var (
 	AccessManager     = []string{core.LevelManager}
 	AccessUser        = []string{core.LevelUser}
 	AccessManagerUser = []string{core.LevelManager, core.LevelUser}
+	AccessRootManager = []string{core.LevelRoot, core.LevelManager}
 	AccessAll         = []string{core.LevelRoot, core.LevelManager, core.LevelUser}
 )
 
 var (
 	missingPathParamUserID = errors.BadRequest("Missing path param 'userID'")
 	missingPathParamLotID  = errors.BadRequest("Missing path param 'lotID'")
+
+	missingPathParamTemplateID = errors.BadRequest("Missing path param 'templateID'")
 )
 
 type eject interface {
@@ -32,6 +36,10 @@ type ejectLot interface {
 	ejectLot(ctx echo.Context) error
 }
 
+type ejectTemplate interface {
+	ejectTemplate(ctx echo.Context) error
+}
+
 type Command interface {
 	Command() string
 	Access() bool
@@ -123,6 +131,30 @@ func (cmd *lot) ejectLot(ctx echo.Context) error {
 	return nil
 }
 
+type template struct {
+	*base
+	TemplateId uint `json:"template_id" validate:"required"`
+}
+
+func newTemplate(name string, levels []string, user *core.User) *template {
+	return &template{
+		base: newBase(name, levels, user),
+	}
+}
+
+func (cmd *template) TemplateID() uint {
+	return cmd.TemplateId
+}
+
+func (cmd *template) ejectTemplate(ctx echo.Context) error {
+	templateID, err := strconv.Atoi(ctx.Param("templateID"))
+	if err != nil {
+		return missingPathParamTemplateID
+	}
+	cmd.TemplateId = uint(templateID)
+	return nil
+}
+
 type Event interface {
 	Event() string
 	Code() int
@@ -156,6 +188,17 @@ func LotEvent(name string, lot *core.Lot) Event {
 	}{newEvent(name, http.StatusOK), lot}
 }
 
+// RawEvent is the event of the command executed by another instance of the
+// auction, its payload is already prepared for the executor.
+type RawEvent struct {
+	*event
+	Payload json.RawMessage
+}
+
+func NewRawEvent(cmd Command, code int, payload json.RawMessage) *RawEvent {
+	return &RawEvent{newSucces(cmd.commandName(), code), payload}
+}
+
 func ErrorEvent(name string, err error) Event {
 	return &struct {
 		*event
@@ -202,6 +245,10 @@ func New(name string, user *core.User) Command {
 		return newGetLots(user)
 	case CommandAddLot:
 		return newAddLot(user)
+	case CommandAddLotsBulk:
+		return newAddLotsBulk(user)
+	case CommandPreviewRules:
+		return newPreviewRules(user)
 	case CommandGetLot:
 		return newGetLot(user)
 	case CommandEditLot:
@@ -212,6 +259,10 @@ func New(name string, user *core.User) Command {
 		return newPlaceBet(user)
 	case CommandCancelBet:
 		return newCancelBet(user)
+	case CommandPlaceProxy:
+		return newPlaceProxy(user)
+	case CommandCancelProxy:
+		return newCancelProxy(user)
 	case CommandConfirmLot:
 		return newConfirmLot(user)
 	case CommandDeleteConfirmation:
@@ -222,8 +273,28 @@ func New(name string, user *core.User) Command {
 		return newPlaceReservation(user)
 	case CommandCancelReservation:
 		return newCancelReservation(user)
+	case CommandGetTemplates:
+		return newGetTemplates(user)
+	case CommandAddTemplate:
+		return newAddTemplate(user)
+	case CommandGetTemplate:
+		return newGetTemplate(user)
+	case CommandEditTemplate:
+		return newEditTemplate(user)
+	case CommandDeleteTemplate:
+		return newDeleteTemplate(user)
 	case CommandGetHistory:
 		return newGetHistory(user)
+	case CommandGetLotEvents:
+		return newGetLotEvents(user)
+	case CommandReplayLot:
+		return newReplayLot(user)
+	case CommandGetProcesses:
+		return newGetProcesses(user)
+	case CommandGetProcess:
+		return newGetProcess(user)
+	case CommandAdvanceClock:
+		return newAdvanceClock(user)
 	case CommandAcceptBet:
 		return newAcceptBet(user)
 	case CommandSendFeedback:
@@ -247,6 +318,11 @@ func Fill(cmd Command, ctx echo.Context) error {
 			return err
 		}
 	}
+	if eject, ok := cmd.(ejectTemplate); ok {
+		if err := eject.ejectTemplate(ctx); err != nil {
+			return err
+		}
+	}
 	if eject, ok := cmd.(eject); ok {
 		if err := eject.eject(ctx); err != nil {
 			return err
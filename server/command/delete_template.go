@@ -0,0 +1,17 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+)
+
+const CommandDeleteTemplate = "delete.template"
+
+type DeleteTemplate struct {
+	*template
+}
+
+func newDeleteTemplate(user *core.User) *DeleteTemplate {
+	return &DeleteTemplate{
+		template: newTemplate(CommandDeleteTemplate, AccessManager, user),
+	}
+}
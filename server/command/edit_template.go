@@ -0,0 +1,44 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+const CommandEditTemplate = "edit.template"
+
+type EditTemplate struct {
+	*template
+	*core.LotTemplate
+}
+
+func newEditTemplate(user *core.User) *EditTemplate {
+	return &EditTemplate{
+		template:    newTemplate(CommandEditTemplate, AccessManager, user),
+		LotTemplate: &core.LotTemplate{},
+	}
+}
+
+func (cmd *EditTemplate) GetTemplate() *core.LotTemplate {
+	return cmd.LotTemplate
+}
+
+func (cmd *EditTemplate) SetTemplate(template *core.LotTemplate) {
+	cmd.LotTemplate = template
+}
+
+func (cmd *EditTemplate) Event() Event {
+	return &struct {
+		*event
+		*core.LotTemplate
+	}{
+		newSucces(cmd.name, http.StatusOK),
+		cmd.LotTemplate,
+	}
+}
+
+func (cmd *EditTemplate) eject(ctx echo.Context) error {
+	return ctx.Bind(cmd)
+}
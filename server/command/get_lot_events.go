@@ -0,0 +1,38 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+)
+
+const CommandGetLotEvents = "get.lot_events"
+
+type GetLotEvents struct {
+	*lot
+	events []*core.LotEvent
+}
+
+func newGetLotEvents(user *core.User) *GetLotEvents {
+	return &GetLotEvents{
+		lot: newLot(CommandGetLotEvents, AccessRoot, user),
+	}
+}
+
+func (cmd *GetLotEvents) SetLotEvents(events []*core.LotEvent) {
+	cmd.events = events
+}
+
+func (cmd *GetLotEvents) Event() Event {
+	e := eventGetLotEvents(cmd.events)
+	return &e
+}
+
+type eventGetLotEvents []*core.LotEvent
+
+func (evt *eventGetLotEvents) Event() string {
+	return successName(CommandGetLotEvents)
+}
+
+func (evt *eventGetLotEvents) Code() int {
+	return http.StatusOK
+}
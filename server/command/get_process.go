@@ -0,0 +1,33 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+)
+
+const CommandGetProcess = "get.process"
+
+type GetProcess struct {
+	*lot
+	state *core.ProcessState
+}
+
+func newGetProcess(user *core.User) *GetProcess {
+	return &GetProcess{
+		lot: newLot(CommandGetProcess, AccessRoot, user),
+	}
+}
+
+func (cmd *GetProcess) SetProcessState(state *core.ProcessState) {
+	cmd.state = state
+}
+
+func (cmd *GetProcess) Event() Event {
+	return &struct {
+		*event
+		*core.ProcessState
+	}{
+		newSucces(cmd.name, http.StatusOK),
+		cmd.state,
+	}
+}
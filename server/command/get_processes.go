@@ -0,0 +1,33 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+)
+
+const CommandGetProcesses = "get.processes"
+
+type GetProcesses struct {
+	*base
+	info *core.ProcessesInfo
+}
+
+func newGetProcesses(user *core.User) *GetProcesses {
+	return &GetProcesses{
+		base: newBase(CommandGetProcesses, AccessRoot, user),
+	}
+}
+
+func (cmd *GetProcesses) SetProcesses(info *core.ProcessesInfo) {
+	cmd.info = info
+}
+
+func (cmd *GetProcesses) Event() Event {
+	return &struct {
+		*event
+		*core.ProcessesInfo
+	}{
+		newSucces(cmd.name, http.StatusOK),
+		cmd.info,
+	}
+}
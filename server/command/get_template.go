@@ -0,0 +1,33 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+)
+
+const CommandGetTemplate = "get.template"
+
+type GetTemplate struct {
+	*template
+	t *core.LotTemplate
+}
+
+func newGetTemplate(user *core.User) *GetTemplate {
+	return &GetTemplate{
+		template: newTemplate(CommandGetTemplate, AccessManager, user),
+	}
+}
+
+func (cmd *GetTemplate) SetTemplate(template *core.LotTemplate) {
+	cmd.t = template
+}
+
+func (cmd *GetTemplate) Event() Event {
+	return &struct {
+		*event
+		*core.LotTemplate
+	}{
+		newSucces(cmd.name, http.StatusOK),
+		cmd.t,
+	}
+}
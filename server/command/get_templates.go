@@ -0,0 +1,38 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+)
+
+const CommandGetTemplates = "get.templates"
+
+type GetTemplates struct {
+	*base
+	templates []*core.LotTemplate
+}
+
+func newGetTemplates(user *core.User) *GetTemplates {
+	return &GetTemplates{
+		base: newBase(CommandGetTemplates, AccessManager, user),
+	}
+}
+
+func (cmd *GetTemplates) SetTemplates(templates []*core.LotTemplate) {
+	cmd.templates = templates
+}
+
+func (cmd *GetTemplates) Event() Event {
+	e := eventGetTemplates(cmd.templates)
+	return &e
+}
+
+type eventGetTemplates []*core.LotTemplate
+
+func (evt *eventGetTemplates) Event() string {
+	return successName(CommandGetTemplates)
+}
+
+func (evt *eventGetTemplates) Code() int {
+	return http.StatusOK
+}
@@ -14,7 +14,7 @@ type GetUsers struct {
 
 func newGetUsers(user *core.User) *GetUsers {
 	return &GetUsers{
-		base: newBase(CommandGetUsers, AccessRoot, user),
+		base: newBase(CommandGetUsers, AccessRootManager, user),
 	}
 }
 
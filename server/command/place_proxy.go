@@ -0,0 +1,37 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+
+	"github.com/labstack/echo"
+)
+
+const CommandPlaceProxy = "place.proxy"
+
+type PlaceProxy struct {
+	*lot
+	l          *core.Lot
+	ProxyFloor uint `json:"floor" validate:"required,gt=0"`
+}
+
+func newPlaceProxy(user *core.User) *PlaceProxy {
+	return &PlaceProxy{
+		lot: newLot(CommandPlaceProxy, AccessUser, user),
+	}
+}
+
+func (cmd *PlaceProxy) Floor() uint {
+	return cmd.ProxyFloor
+}
+
+func (cmd *PlaceProxy) GetLot() *core.Lot {
+	return cmd.l
+}
+
+func (cmd *PlaceProxy) SetLot(lot *core.Lot) {
+	cmd.l = lot
+}
+
+func (cmd *PlaceProxy) eject(ctx echo.Context) error {
+	return ctx.Bind(cmd)
+}
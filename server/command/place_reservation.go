@@ -6,6 +6,7 @@ const CommandPlaceReservation = "place.reservation"
 
 type PlaceReservation struct {
 	*lot
+	l *core.Lot
 }
 
 func newPlaceReservation(user *core.User) *PlaceReservation {
@@ -13,3 +14,11 @@ func newPlaceReservation(user *core.User) *PlaceReservation {
 		lot: newLot(CommandPlaceReservation, AccessUser, user),
 	}
 }
+
+func (cmd *PlaceReservation) GetLot() *core.Lot {
+	return cmd.l
+}
+
+func (cmd *PlaceReservation) SetLot(lot *core.Lot) {
+	cmd.l = lot
+}
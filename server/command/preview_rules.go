@@ -0,0 +1,59 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+const CommandPreviewRules = "preview.rules"
+
+type PreviewRules struct {
+	*base
+	Rules    core.Rules `json:"rules" validate:"required,min=1"`
+	GroupKey string     `json:"group_key"`
+	TimeZone string     `json:"time_zone" validate:"omitempty,time_zone"`
+	Date     string     `json:"date"`
+	preview  *core.RulePreview
+}
+
+func newPreviewRules(user *core.User) *PreviewRules {
+	return &PreviewRules{
+		base: newBase(CommandPreviewRules, AccessManager, user),
+	}
+}
+
+func (cmd *PreviewRules) GetRules() core.Rules {
+	return cmd.Rules
+}
+
+func (cmd *PreviewRules) Group() string {
+	return cmd.GroupKey
+}
+
+func (cmd *PreviewRules) Zone() string {
+	return cmd.TimeZone
+}
+
+func (cmd *PreviewRules) Day() string {
+	return cmd.Date
+}
+
+func (cmd *PreviewRules) SetPreview(preview *core.RulePreview) {
+	cmd.preview = preview
+}
+
+func (cmd *PreviewRules) Event() Event {
+	return &struct {
+		*event
+		*core.RulePreview
+	}{
+		newSucces(cmd.name, http.StatusOK),
+		cmd.preview,
+	}
+}
+
+func (cmd *PreviewRules) eject(ctx echo.Context) error {
+	return ctx.Bind(cmd)
+}
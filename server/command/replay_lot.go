@@ -0,0 +1,49 @@
+package command
+
+import (
+	"gitlab/nefco/auction/core"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+const CommandReplayLot = "replay.lot"
+
+type ReplayLot struct {
+	*lot
+	l          *core.Lot
+	LotVersion uint `json:"version"`
+	LotSave    bool `json:"save"`
+}
+
+func newReplayLot(user *core.User) *ReplayLot {
+	return &ReplayLot{
+		lot: newLot(CommandReplayLot, AccessRoot, user),
+	}
+}
+
+func (cmd *ReplayLot) Version() uint {
+	return cmd.LotVersion
+}
+
+func (cmd *ReplayLot) Save() bool {
+	return cmd.LotSave
+}
+
+func (cmd *ReplayLot) SetLot(lot *core.Lot) {
+	cmd.l = lot
+}
+
+func (cmd *ReplayLot) eject(ctx echo.Context) error {
+	return ctx.Bind(cmd)
+}
+
+func (cmd *ReplayLot) Event() Event {
+	return &struct {
+		*event
+		*core.Lot
+	}{
+		newSucces(cmd.name, http.StatusOK),
+		cmd.l,
+	}
+}
@@ -9,6 +9,7 @@ type Config struct {
 	JWTSecret         string        `mapstructure:"jwt_secret"`
 	ManagerJWTExpires time.Duration `mapstructure:"manager_jwt_expires"`
 	UserJWTExpires    time.Duration `mapstructure:"user_jwt_expires"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`
 }
 
 func DefaultConfig() *Config {
@@ -17,5 +18,6 @@ func DefaultConfig() *Config {
 		JWTSecret:         "secret",
 		ManagerJWTExpires: 24 * 365 * 10 * time.Hour,
 		UserJWTExpires:    24 * time.Hour,
+		ShutdownTimeout:   30 * time.Second,
 	}
 }
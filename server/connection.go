@@ -35,6 +35,7 @@ const (
 
 	eventSystemError        = "event.system.error"
 	eventSystemInvalidToken = "event.system.invalid.token"
+	eventSystemShutdown     = "event.system.shutdown"
 )
 
 var (
@@ -48,17 +49,20 @@ var (
 
 type connection struct {
 	user      *core.User
+	token     string
 	auc       auction.Auction
 	expiresAt time.Time
 	mng       *connectionManager
+	gate      *gate
 	wsConn    *websocket.Conn
 	chMsg     chan json.RawMessage
+	closing   chan struct{}
 	logger    *zap.Logger
 }
 
 func newConnection(
-	user *core.User, auc auction.Auction,
-	mng *connectionManager,
+	user *core.User, token string, auc auction.Auction,
+	mng *connectionManager, gate *gate,
 	req *http.Request, resp http.ResponseWriter,
 	expiresAt time.Time) error {
 	wsConn, err := upgrader.Upgrade(resp, req, nil)
@@ -70,11 +74,14 @@ func newConnection(
 
 	conn := &connection{
 		user:      user,
+		token:     token,
 		auc:       auc,
 		expiresAt: expiresAt,
 		mng:       mng,
+		gate:      gate,
 		wsConn:    wsConn,
 		chMsg:     make(chan json.RawMessage),
+		closing:   make(chan struct{}),
 		logger:    zap.L().Named("connection").With(zap.Uint("user_id", user.ID)),
 	}
 
@@ -99,6 +106,14 @@ func (conn *connection) prepareMsg(evt command.Event) (json.RawMessage, error) {
 			Type:  evt.Event(),
 			Error: err.Error(),
 		}
+	} else if raw, ok := evt.(*command.RawEvent); ok {
+		msg = struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}{
+			Type:    evt.Event(),
+			Payload: raw.Payload,
+		}
 	} else {
 		res, err := response(conn.user, evt)
 		if err != nil {
@@ -128,7 +143,10 @@ func (conn *connection) send(evt command.Event) error {
 	if err != nil {
 		return err
 	}
-	conn.chMsg <- msg
+	select {
+	case conn.chMsg <- msg:
+	case <-conn.closing:
+	}
 	return nil
 }
 
@@ -192,7 +210,10 @@ func (conn *connection) readPump() {
 			zap.String("type", cmd.Command()),
 		)
 
-		evt, err := execute(cmd, conn.auc)
+		evt, err := conn.gate.execute(cmd, conn.auc)
+		if remote, ok := err.(*auction.RemoteLot); ok {
+			evt, err = forward(cmd, conn.token, remote)
+		}
 		if err != nil {
 			conn.send(command.Fail(cmd, err))
 			continue
@@ -218,6 +239,8 @@ func (conn *connection) writePump() {
 		conn.wsConn.Close()
 	}()
 
+	closing := conn.closing
+
 	for {
 		select {
 		case msg, ok := <-conn.chMsg:
@@ -264,6 +287,35 @@ func (conn *connection) writePump() {
 				logger.Error("write close failed", zap.Error(err))
 				return
 			}
+		case <-closing:
+			closing = nil
+
+			logger.Info("shutdown")
+
+			evt := command.ErrorEvent(eventSystemShutdown, serverShutdown)
+
+			msg, err := conn.prepareMsg(evt)
+			if err != nil {
+				logger.Error("prepare msg failed", zap.Error(err))
+				return
+			}
+
+			conn.wsConn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.wsConn.WriteJSON(msg); err != nil {
+				logger.Error("write failed", zap.Error(err))
+				return
+			}
+
+			err = conn.wsConn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+			if err != nil {
+				logger.Error("write close failed", zap.Error(err))
+				return
+			}
+
+			// the answer of the client to the close message ends readPump,
+			// which unregisters the connection
+			conn.wsConn.SetReadDeadline(time.Now().Add(writeWait))
 		}
 	}
 }
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+
 	"go.uber.org/zap"
 )
 
@@ -8,6 +10,8 @@ type connectionManager struct {
 	connMap    map[*connection]bool
 	register   chan *connection
 	unregister chan *connection
+	shutdown   chan struct{}
+	closed     chan struct{}
 	logger     *zap.Logger
 }
 
@@ -16,12 +20,18 @@ func newConnectionManager() *connectionManager {
 		connMap:    make(map[*connection]bool),
 		register:   make(chan *connection),
 		unregister: make(chan *connection),
+		shutdown:   make(chan struct{}),
+		closed:     make(chan struct{}),
 		logger:     zap.L().Named("connection_manager"),
 	}
 }
 
 func (mng *connectionManager) run() {
 	logger := mng.logger.Named("run")
+
+	shutdown := mng.shutdown
+	closing := false
+
 	for {
 		select {
 		case conn := <-mng.register:
@@ -29,6 +39,9 @@ func (mng *connectionManager) run() {
 			logger.Debug("register",
 				zap.Uint("user_id", conn.user.ID),
 			)
+			if closing {
+				close(conn.closing)
+			}
 		case conn := <-mng.unregister:
 			if _, ok := mng.connMap[conn]; ok {
 				delete(mng.connMap, conn)
@@ -37,6 +50,31 @@ func (mng *connectionManager) run() {
 					zap.Uint("user_id", conn.user.ID),
 				)
 			}
+		case <-shutdown:
+			shutdown = nil
+			closing = true
+			logger.Info("shutdown", zap.Int("count", len(mng.connMap)))
+			for conn := range mng.connMap {
+				close(conn.closing)
+			}
 		}
+
+		if closing && len(mng.connMap) == 0 {
+			close(mng.closed)
+			return
+		}
+	}
+}
+
+// close sends the shutdown event to the connections, closes them and waits
+// till they are unregistered.
+func (mng *connectionManager) close(ctx context.Context) error {
+	close(mng.shutdown)
+
+	select {
+	case <-mng.closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
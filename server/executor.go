@@ -25,6 +25,7 @@ var validate *validator.Validate
 func init() {
 	validate = validator.New()
 	core.ValidateState(validate)
+	core.ValidateTimeZone(validate)
 	object.ValidateObjectType(validate)
 	rule.ValidateRule(validate)
 }
@@ -74,6 +75,11 @@ func execute(cmd command.Command, auction auction.Auction) (command.Event, error
 		err = auction.CreateLot(c)
 	case *command.AutoBooking:
 		err = auction.AutoBookingLot(c)
+	case *command.AddLotsBulk:
+		c.Validate(validate)
+		err = auction.CreateLots(c)
+	case *command.PreviewRules:
+		err = auction.PreviewRules(c)
 	case *command.GetLot:
 		err = auction.GetLot(c)
 	case *command.EditLot:
@@ -84,6 +90,10 @@ func execute(cmd command.Command, auction auction.Auction) (command.Event, error
 		err = auction.PlaceBet(c)
 	case *command.CancelBet:
 		err = auction.CancelBet(c)
+	case *command.PlaceProxy:
+		err = auction.PlaceProxy(c)
+	case *command.CancelProxy:
+		err = auction.CancelProxy(c)
 	case *command.ConfirmLot:
 		err = auction.ConfirmLot(c)
 	case *command.DeleteConfirmation:
@@ -92,8 +102,32 @@ func execute(cmd command.Command, auction auction.Auction) (command.Event, error
 		err = auction.CompleteLot(c)
 	case *command.GetHistory:
 		err = auction.History(c)
+	case *command.GetLotEvents:
+		err = auction.LotEvents(c)
+	case *command.ReplayLot:
+		err = auction.ReplayLot(c)
+	case *command.GetProcesses:
+		err = auction.Processes(c)
+	case *command.GetProcess:
+		err = auction.ProcessState(c)
+	case *command.AdvanceClock:
+		err = auction.AdvanceClock(c)
 	case *command.AcceptBet:
 		err = auction.AcceptBet(c)
+	case *command.PlaceReservation:
+		err = auction.PlaceReservation(c)
+	case *command.CancelReservation:
+		err = auction.CancelReservation(c)
+	case *command.GetTemplates:
+		err = auction.Templates(c)
+	case *command.AddTemplate:
+		err = auction.CreateTemplate(c)
+	case *command.GetTemplate:
+		err = auction.GetTemplate(c)
+	case *command.EditTemplate:
+		err = auction.UpdateTemplate(c)
+	case *command.DeleteTemplate:
+		err = auction.DeleteTemplate(c)
 	case *command.SendFeedback:
 		err = auction.SendFeedback(c)
 	case *command.GetBookingWindow:
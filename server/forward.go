@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"gitlab/nefco/auction/auction"
+	"gitlab/nefco/auction/errors"
+	"gitlab/nefco/auction/server/command"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	resty "gopkg.in/resty.v1"
+)
+
+var (
+	lotNotLeased  = errors.NewError("Lot not leased", http.StatusServiceUnavailable)
+	forwardFailed = errors.NewError("Forward failed", http.StatusBadGateway)
+)
+
+// forward executes the command by the instance which runs the lot. The
+// command is sent as the payload of WS command with the token of the
+// executor, so the instance fills it the same way.
+func forward(cmd command.Command, token string,
+	remote *auction.RemoteLot) (command.Event, error) {
+	logger := zap.L().Named("forward").With(
+		zap.String("command", cmd.Command()),
+		zap.Uint("lot_id", remote.LotID),
+		zap.String("instance", remote.Instance),
+	)
+
+	resp, err := resty.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", "Bearer "+token).
+		SetBody(cmd).
+		Post(strings.TrimRight(remote.Address, "/") + "/commands/" + cmd.Command())
+	if err != nil {
+		logger.Error("post failed", zap.Error(err))
+		return nil, forwardFailed
+	}
+
+	if resp.StatusCode() >= http.StatusBadRequest {
+		e := errors.NewError(http.StatusText(resp.StatusCode()), resp.StatusCode())
+		if err := json.Unmarshal(resp.Body(), e); err != nil {
+			logger.Warn("decode error failed", zap.Error(err))
+		}
+		return nil, e
+	}
+
+	logger.Debug("forwarded", zap.Int("code", resp.StatusCode()))
+
+	return command.NewRawEvent(cmd, resp.StatusCode(), resp.Body()), nil
+}
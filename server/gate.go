@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"gitlab/nefco/auction/auction"
+	"gitlab/nefco/auction/server/command"
+	"sync"
+)
+
+// gate executes the commands of HTTP and WS handlers. On shutdown it
+// rejects the new commands and waits for the commands being executed.
+type gate struct {
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newGate() *gate {
+	return &gate{}
+}
+
+func (g *gate) execute(cmd command.Command,
+	auction auction.Auction) (command.Event, error) {
+	g.mu.RLock()
+	if g.closed {
+		g.mu.RUnlock()
+		return nil, serverShutdown
+	}
+	g.wg.Add(1)
+	g.mu.RUnlock()
+
+	defer g.wg.Done()
+
+	return execute(cmd, auction)
+}
+
+func (g *gate) close(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
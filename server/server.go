@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"gitlab/nefco/auction/auction"
 	"gitlab/nefco/auction/core"
@@ -25,6 +27,7 @@ var (
 	invalidToken        = errors.BadRequest("Invalid token")
 	invalidCommand      = errors.BadRequest("Invalid command")
 	invalidRequest      = errors.BadRequest("Invalid request")
+	serverShutdown      = errors.NewError("Server shutdown", http.StatusServiceUnavailable)
 )
 
 type NotifyReader interface {
@@ -41,15 +44,18 @@ type server struct {
 	auction     auction.Auction
 	notify      NotifyReader
 	connManager *connectionManager
+	gate        *gate
+	e           *echo.Echo
 	logger      *zap.Logger
 }
 
-func NewServer(conf *Config, auction auction.Auction, notify NotifyReader) error {
+func NewServer(conf *Config, auction auction.Auction, notify NotifyReader) *server {
 	srv := &server{
 		conf:        conf,
 		auction:     auction,
 		notify:      notify,
 		connManager: newConnectionManager(),
+		gate:        newGate(),
 		logger:      zap.L().Named("server"),
 	}
 
@@ -78,6 +84,8 @@ func NewServer(conf *Config, auction auction.Auction, notify NotifyReader) error
 	api.GET("lots", srv.httpHandler(command.CommandGetLots))
 	api.POST("lots", srv.httpHandler(command.CommandAddLot))
 	api.POST("lots/auto_booking", srv.httpHandler(command.CommandAutoBooking))
+	api.POST("lots/bulk", srv.httpHandler(command.CommandAddLotsBulk))
+	api.POST("lots/rules/preview", srv.httpHandler(command.CommandPreviewRules))
 	api.GET("lots/:lotID", srv.httpHandler(command.CommandGetLot))
 	api.PATCH("lots/:lotID", srv.httpHandler(command.CommandEditLot))
 	api.DELETE("lots/:lotID", srv.httpHandler(command.CommandDeleteLot))
@@ -85,6 +93,8 @@ func NewServer(conf *Config, auction auction.Auction, notify NotifyReader) error
 	api.PATCH("user/:userID/unblock", srv.httpHandler(command.CommandUnblockUser))
 	api.POST("lots/:lotID/bet", srv.httpHandler(command.CommandPlaceBet))
 	api.DELETE("lots/:lotID/bet", srv.httpHandler(command.CommandCancelBet))
+	api.PUT("lots/:lotID/proxy", srv.httpHandler(command.CommandPlaceProxy))
+	api.DELETE("lots/:lotID/proxy", srv.httpHandler(command.CommandCancelProxy))
 	api.PUT("lots/:lotID/confirm", srv.httpHandler(command.CommandConfirmLot))
 	api.PATCH("lots/:lotID/confirm", srv.httpHandler(command.CommandEditConfirmation))
 	api.DELETE("lots/:lotID/confirm", srv.httpHandler(command.CommandDeleteConfirmation))
@@ -92,9 +102,20 @@ func NewServer(conf *Config, auction auction.Auction, notify NotifyReader) error
 	api.POST("lots/:lotID/reservation", srv.httpHandler(command.CommandPlaceReservation))
 	api.DELETE("lots/:lotID/reservation", srv.httpHandler(command.CommandCancelReservation))
 	api.GET("lots/:lotID/history", srv.httpHandler(command.CommandGetHistory))
+	api.GET("lots/:lotID/events", srv.httpHandler(command.CommandGetLotEvents))
+	api.POST("lots/:lotID/replay", srv.httpHandler(command.CommandReplayLot))
 	api.PUT("lots/:lotID/bets/:betID/accept", srv.httpHandler(command.CommandAcceptBet))
 	api.POST("feedback", srv.httpHandler(command.CommandSendFeedback))
 	api.GET("lots/:lotID/windows", srv.httpHandler(command.CommandGetBookingWindow))
+	api.POST("commands/:command", srv.commandHandler)
+	api.GET("processes", srv.httpHandler(command.CommandGetProcesses))
+	api.GET("processes/:lotID", srv.httpHandler(command.CommandGetProcess))
+	api.POST("clock/advance", srv.httpHandler(command.CommandAdvanceClock))
+	api.GET("templates", srv.httpHandler(command.CommandGetTemplates))
+	api.POST("templates", srv.httpHandler(command.CommandAddTemplate))
+	api.GET("templates/:templateID", srv.httpHandler(command.CommandGetTemplate))
+	api.PATCH("templates/:templateID", srv.httpHandler(command.CommandEditTemplate))
+	api.DELETE("templates/:templateID", srv.httpHandler(command.CommandDeleteTemplate))
 
 	// acts
 	api.PATCH("lots/:lotID/act", srv.httpHandler(command.CommandEditAct))
@@ -116,7 +137,57 @@ func NewServer(conf *Config, auction auction.Auction, notify NotifyReader) error
 
 	e.Logger.SetLevel(log.OFF)
 
-	return e.Start(fmt.Sprintf(":%d", conf.Port))
+	srv.e = e
+
+	return srv
+}
+
+// Start serves the requests till the shutdown.
+func (srv *server) Start() error {
+	return srv.e.Start(fmt.Sprintf(":%d", srv.conf.Port))
+}
+
+// Shutdown stops the new commands and waits for the commands being
+// executed, then stops the processes of the auction without stopping their
+// rules. The WS clients are sent the shutdown event before their
+// connections are closed. Every step is run even if the previous one fails
+// or the context is done, the first error is returned.
+func (srv *server) Shutdown(ctx context.Context) error {
+	logger := srv.logger.Named("shutdown")
+
+	var result error
+
+	fail := func(err error) {
+		if result == nil {
+			result = err
+		}
+	}
+
+	if err := srv.gate.close(ctx); err != nil {
+		logger.Error("wait commands failed", zap.Error(err))
+		fail(err)
+	}
+
+	if err := srv.e.Shutdown(ctx); err != nil {
+		logger.Error("http shutdown failed", zap.Error(err))
+		fail(err)
+
+		if err := srv.e.Close(); err != nil {
+			logger.Error("http close failed", zap.Error(err))
+		}
+	}
+
+	if err := srv.auction.Shutdown(ctx); err != nil {
+		logger.Error("auction shutdown failed", zap.Error(err))
+		fail(err)
+	}
+
+	if err := srv.connManager.close(ctx); err != nil {
+		logger.Error("close connections failed", zap.Error(err))
+		fail(err)
+	}
+
+	return result
 }
 
 func (srv *server) loginHandler(ctx echo.Context) error {
@@ -193,7 +264,10 @@ func (srv *server) httpHandler(commandType string) echo.HandlerFunc {
 			zap.String("type", cmd.Command()),
 		)
 
-		evt, err := execute(cmd, srv.auction)
+		evt, err := srv.gate.execute(cmd, srv.auction)
+		if remote, ok := err.(*auction.RemoteLot); ok {
+			evt, err = forward(cmd, ejectToken(ctx), remote)
+		}
 		if err != nil {
 			return err
 		}
@@ -202,18 +276,68 @@ func (srv *server) httpHandler(commandType string) echo.HandlerFunc {
 			zap.String("type", evt.Event()),
 		)
 
-		res, err := response(cmd.Executor(), evt)
-		if err != nil {
-			logger.Error("response prepare failed", zap.Error(err))
-			return err
-		}
+		return respond(ctx, cmd, evt)
+	}
+}
 
-		if evt.Code() == http.StatusNoContent {
-			return ctx.NoContent(evt.Code())
-		}
+// commandHandler executes the command forwarded by another instance, the
+// body is the payload of WS command. The command is not forwarded again.
+func (srv *server) commandHandler(ctx echo.Context) error {
+	logger := srv.logger.Named("command_handler")
 
-		return ctx.JSON(evt.Code(), res)
+	claims, err := ejectClaims(ctx)
+	if err != nil {
+		logger.Error("invalid token")
+		return invalidToken
+	}
+
+	user := claims.User
+
+	logger = logger.With(zap.Uint("user_id", user.ID))
+
+	cmd := command.New(command.ExtractCommand(ctx.Param("command")), &user)
+	if cmd == nil {
+		logger.Error("invalid command")
+		return invalidCommand
+	}
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(cmd); err != nil {
+		logger.Error("invalid request", zap.Error(err))
+		return invalidRequest
 	}
+
+	logger.Debug("command",
+		zap.String("type", cmd.Command()),
+	)
+
+	evt, err := srv.gate.execute(cmd, srv.auction)
+	if _, ok := err.(*auction.RemoteLot); ok {
+		logger.Warn("lot not leased", zap.Error(err))
+		return lotNotLeased
+	}
+	if err != nil {
+		return err
+	}
+
+	return respond(ctx, cmd, evt)
+}
+
+func respond(ctx echo.Context, cmd command.Command, evt command.Event) error {
+	if evt.Code() == http.StatusNoContent {
+		return ctx.NoContent(evt.Code())
+	}
+
+	if raw, ok := evt.(*command.RawEvent); ok {
+		return ctx.JSONBlob(raw.Code(), raw.Payload)
+	}
+
+	res, err := response(cmd.Executor(), evt)
+	if err != nil {
+		zap.L().Named("respond").Error("response prepare failed", zap.Error(err))
+		return err
+	}
+
+	return ctx.JSON(evt.Code(), res)
 }
 
 func (srv *server) wsHandler(ctx echo.Context) error {
@@ -229,8 +353,10 @@ func (srv *server) wsHandler(ctx echo.Context) error {
 
 	err = newConnection(
 		&user,
+		ejectToken(ctx),
 		srv.auction,
 		srv.connManager,
+		srv.gate,
 		ctx.Request(),
 		ctx.Response(),
 		time.Unix(claims.ExpiresAt, 0))
@@ -264,6 +390,13 @@ func ejectClaims(ctx echo.Context) (*jwtClaims, error) {
 	return nil, errors.New("eject user_id from token failed")
 }
 
+func ejectToken(ctx echo.Context) string {
+	if token, ok := ctx.Get("user").(*jwt.Token); ok {
+		return token.Raw
+	}
+	return ""
+}
+
 func broadcast(mng *connectionManager, ntf NotifyReader) {
 	logger := zap.L().Named("broadcast")
 	for n := range ntf.Events() {
@@ -74,11 +74,13 @@ func (svc backService) SetDateBook(t time.Time, bet *core.Bet) error {
 		ObjectID   uint          `json:"objectID"`
 		DateBooked core.JSONTime `json:"date_booked"`
 		UserID     uint          `json:"userID"`
+		Value      uint          `json:"value"`
 		*core.Bet
 	}{
 		svc.lot.ObjectID,
 		core.JSONTime(t),
 		svc.executor.ID,
+		bet.Price(),
 		bet,
 	}
 
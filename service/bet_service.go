@@ -33,6 +33,8 @@ func (svc *betService) MinBet(lotID uint) (*core.Bet, error) {
 			id,
 			value,
 			winner,
+			settled,
+			auto,
 			created_at,
 			deleted_at,
 			lot_id,
@@ -69,6 +71,8 @@ func (svc *betService) Bets(lotID uint) ([]*core.Bet, error) {
 			id,
 			value,
 			winner,
+			settled,
+			auto,
 			created_at,
 			deleted_at,
 			lot_id,
@@ -110,12 +114,13 @@ func (svc *betService) Bet(betID uint) (*core.Bet, error) {
 }
 
 func (svc *betService) CreateBet(bet *core.Bet) error {
-	bet.CreatedAt = now()
+	bet.CreatedAt = svc.tx.Now()
 
 	query := `
 		INSERT INTO bets (
 			value, 
 			winner,
+			auto,
 			created_at,
 			lot_id, 
 			user_id
@@ -123,6 +128,7 @@ func (svc *betService) CreateBet(bet *core.Bet) error {
 		VALUES (
 			:value,
 			:winner, 
+			:auto,
 			:created_at,
 			:lot_id, 
 			:user_id
@@ -145,7 +151,7 @@ func (svc *betService) CreateBet(bet *core.Bet) error {
 }
 
 func (svc *betService) SaveBet(bet *core.Bet) error {
-	query := `UPDATE bets SET winner = :winner WHERE id = :id`
+	query := `UPDATE bets SET winner = :winner, settled = :settled WHERE id = :id`
 
 	_, err := svc.tx.Exec(query, bet)
 	if err != nil {
@@ -156,7 +162,7 @@ func (svc *betService) SaveBet(bet *core.Bet) error {
 }
 
 func (svc *betService) DeleteBet(bet *core.Bet) error {
-	n := now()
+	n := svc.tx.Now()
 
 	bet.DeletedAt = &n
 
@@ -170,12 +176,32 @@ func (svc *betService) DeleteBet(bet *core.Bet) error {
 	return nil
 }
 
+// RestoreBet writes the bet back as it was, the deleted bet is restored.
+func (svc *betService) RestoreBet(bet *core.Bet) error {
+	query := `
+		UPDATE bets SET 
+			value = :value,
+			winner = :winner,
+			settled = :settled,
+			auto = :auto,
+			deleted_at = :deleted_at
+		WHERE id = :id 
+		AND lot_id = :lot_id`
+
+	_, err := svc.tx.Exec(query, bet)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (svc *betService) ClearBets(lotID uint) error {
 	arg := struct {
 		DeletedAt time.Time `db:"deleted_at"`
 		LotID     uint      `db:"lot_id"`
 	}{
-		now(),
+		svc.tx.Now(),
 		lotID,
 	}
 
@@ -198,7 +224,7 @@ func (svc *betService) ClearBetsBefore(lotID uint, t time.Time) error {
 		LotID     uint      `db:"lot_id"`
 		CreatedAt time.Time `db:"created_at"`
 	}{
-		now(),
+		svc.tx.Now(),
 		lotID,
 		t,
 	}
@@ -222,6 +248,8 @@ func (svc *betService) GetWinnerBetByLotId(lot *core.Lot) (*core.Bet, error) {
 		SELECT 
 			value, 
 			winner,
+			settled,
+			auto,
 			created_at,
 			lot_id, 
 			user_id
@@ -58,12 +58,14 @@ func (svc *groupService) CreateGroup(group *core.Group) error {
 		INSERT INTO groups (
 			group_key, 
 			name,
-			object_type
+			object_type,
+			time_zone
 		)
 		VALUES (
 			:group_key, 
 			:name,
-			:object_type
+			:object_type,
+			:time_zone
 		)
 	`
 
@@ -7,6 +7,10 @@ import (
 	"gitlab/nefco/auction/interfaces"
 )
 
+// bookingRule is the name of the confirm rule, during which the lot is
+// booked to the carrier.
+const bookingRule = "confirm"
+
 type historyService struct {
 	tx *db.Tx
 }
@@ -43,12 +47,30 @@ func (svc *historyService) BetPlaced(userID uint, lot *core.Lot) error {
 	return svc.create(core.HistoryBetPlaced, userID, lot)
 }
 
+// ProxyBetPlaced records a bet placed automatically on behalf of the user.
+func (svc *historyService) ProxyBetPlaced(userID uint, lot *core.Lot) error {
+	history := newHistory(core.HistoryBetPlaced, userID, lot)
+	history.Auto = true
+	return svc.insert(history, lot)
+}
+
 func (svc *historyService) BetCanceled(userID uint, lot *core.Lot) error {
 	return svc.create(core.HistoryBetCanceled, userID, lot)
 }
 
+// BookingCanceled records the bet canceled by the carrier after the lot was
+// booked to him. It counts as a failure of the carrier.
+func (svc *historyService) BookingCanceled(userID uint, lot *core.Lot) error {
+	history := newHistory(core.HistoryBetCanceled, userID, lot)
+	rule := bookingRule
+	history.Rule = &rule
+	return svc.failure(history, userID, lot)
+}
+
+// LotNoConfirm records the lot not confirmed in time by the carrier userID.
 func (svc *historyService) LotNoConfirm(userID uint, lot *core.Lot) error {
-	return svc.create(core.HistoryLotNoConfirm, core.RootUserID, lot)
+	history := newHistory(core.HistoryLotNoConfirm, core.RootUserID, lot)
+	return svc.failure(history, userID, lot)
 }
 
 func (svc *historyService) LotConfirmed(userID uint, lot *core.Lot) error {
@@ -59,14 +81,40 @@ func (svc *historyService) LotConfirmEdited(userID uint, lot *core.Lot) error {
 	return svc.create(core.HistoryLotConfirmEdited, userID, lot)
 }
 
-func (svc *historyService) LotDeleteConfirmation(userID uint, lot *core.Lot) error {
-	return svc.create(core.HistoryLotDeleteConfirmation, userID, lot)
+func (svc *historyService) LotDeleteConfirmation(userID, carrierID uint,
+	lot *core.Lot) error {
+	history := newHistory(core.HistoryLotDeleteConfirmation, userID, lot)
+	return svc.failure(history, carrierID, lot)
 }
 
 func (svc *historyService) LotBetAccept(userID uint, lot *core.Lot) error {
 	return svc.create(core.HistoryLotBetAccept, userID, lot)
 }
 
+func (svc *historyService) LotReplayed(userID uint, lot *core.Lot) error {
+	return svc.create(core.HistoryLotReplayed, userID, lot)
+}
+
+func (svc *historyService) LotReserved(userID uint, lot *core.Lot) error {
+	return svc.create(core.HistoryLotReserved, userID, lot)
+}
+
+func (svc *historyService) ReservationCanceled(userID uint, lot *core.Lot) error {
+	return svc.create(core.HistoryReservationCanceled, userID, lot)
+}
+
+func (svc *historyService) ReservationExpired(userID uint, lot *core.Lot) error {
+	return svc.create(core.HistoryReservationExpired, core.RootUserID, lot)
+}
+
+func (svc *historyService) LotPublished(userID uint, lot *core.Lot) error {
+	return svc.create(core.HistoryLotPublished, core.RootUserID, lot)
+}
+
+func (svc *historyService) LotExpired(userID uint, lot *core.Lot) error {
+	return svc.create(core.HistoryLotExpired, core.RootUserID, lot)
+}
+
 func (svc *historyService) TheActOfTheLotIsChanged(userID uint, lot *core.Lot) error {
 	return svc.create(core.HistoryActChanged, userID, lot)
 }
@@ -92,22 +140,50 @@ func (svc *historyService) History(lotID uint) ([]*core.History, error) {
 }
 
 func (svc *historyService) create(action string, userID uint, lot *core.Lot) error {
+	return svc.insert(newHistory(action, userID, lot), lot)
+}
+
+// failure records the history of the failure of the carrier and applies
+// the reliability policies to him. Nothing is recorded if there is no
+// carrier.
+func (svc *historyService) failure(history *core.History, carrierID uint,
+	lot *core.Lot) error {
+	if carrierID == 0 {
+		return nil
+	}
+
+	history.CurrentPriceUserID = &carrierID
+
+	if err := svc.insert(history, lot); err != nil {
+		return err
+	}
+
+	return NewReliabilityService(svc.tx).Check(carrierID)
+}
+
+func newHistory(action string, userID uint, lot *core.Lot) *core.History {
 	var currentUserID uint
 
 	if lot.Bet != nil {
 		currentUserID = lot.Bet.UserID
 	}
 
-	history := &core.History{
+	currentPrice := lot.CurrentPrice
+
+	return &core.History{
 		Action:             action,
-		CreatedAt:          now(),
 		LotID:              lot.ID,
 		UserID:             userID,
 		Rule:               &lot.Rule,
 		RulePrice:          &lot.RulePrice,
-		CurrentPrice:       &lot.CurrentPrice,
+		CurrentPrice:       &currentPrice,
 		CurrentPriceUserID: &currentUserID,
 	}
+}
+
+// insert records the history and the lot event of the transition.
+func (svc *historyService) insert(history *core.History, lot *core.Lot) error {
+	history.CreatedAt = svc.tx.Now()
 
 	query := `
 		INSERT INTO history (
@@ -116,6 +192,7 @@ func (svc *historyService) create(action string, userID uint, lot *core.Lot) err
 			rule_price,
 			current_price,
 			current_price_user_id,
+			auto,
 			created_at,
 			lot_id, 
 			user_id
@@ -126,6 +203,7 @@ func (svc *historyService) create(action string, userID uint, lot *core.Lot) err
 			:rule_price,
 			:current_price,
 			:current_price_user_id,
+			:auto,
 			:created_at,
 			:lot_id, 
 			:user_id
@@ -136,5 +214,6 @@ func (svc *historyService) create(action string, userID uint, lot *core.Lot) err
 		return err
 	}
 
-	return nil
+	return NewLotEventService(svc.tx).AppendLotEvent(history.Action,
+		history.UserID, lot)
 }
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+	"time"
+)
+
+type lotChangeService struct {
+	tx *db.Tx
+}
+
+func NewLotChangeService(tx *db.Tx) *lotChangeService {
+	return &lotChangeService{tx}
+}
+
+func (svc *lotChangeService) AppendLotChange(change *core.LotChange) error {
+	change.CreatedAt = svc.tx.Now()
+
+	query := `
+		INSERT INTO lot_changes (
+			kind,
+			instance,
+			state,
+			created_at,
+			lot_id,
+			receiver_id
+		) VALUES (
+			:kind,
+			:instance,
+			:state,
+			:created_at,
+			:lot_id,
+			:receiver_id
+		)
+	`
+
+	if _, err := svc.tx.Exec(query, change); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LotChanges returns the changes after the change made by the other
+// instances, in order of their IDs.
+func (svc *lotChangeService) LotChanges(afterID uint64,
+	instance string) ([]*core.LotChange, error) {
+	changes := []*core.LotChange{}
+
+	arg := map[string]interface{}{
+		"after_id": afterID,
+		"instance": instance,
+	}
+
+	query := `
+		SELECT
+			id,
+			kind,
+			instance,
+			state,
+			created_at,
+			lot_id,
+			receiver_id
+		FROM lot_changes
+		WHERE id > :after_id
+		AND instance <> :instance
+		ORDER BY id
+	`
+
+	if err := svc.tx.Select(&changes, query, arg); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// RemoteLotChanges returns the last changes of the lots leased by the other
+// instances.
+func (svc *lotChangeService) RemoteLotChanges(
+	instance string) ([]*core.LotChange, error) {
+	changes := []*core.LotChange{}
+
+	arg := map[string]interface{}{
+		"instance": instance,
+		"now":      svc.tx.Now(),
+	}
+
+	query := `
+		SELECT
+			c.id,
+			c.kind,
+			c.instance,
+			c.state,
+			c.created_at,
+			c.lot_id,
+			c.receiver_id
+		FROM lot_changes c
+		JOIN lot_leases l ON l.lot_id = c.lot_id
+		WHERE c.id IN (SELECT MAX(id) FROM lot_changes GROUP BY lot_id)
+		AND l.instance <> :instance
+		AND l.expires_at > :now
+	`
+
+	if err := svc.tx.Select(&changes, query, arg); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// LastLotChange returns the ID of the last change, or 0 if there are no
+// changes.
+func (svc *lotChangeService) LastLotChange() (uint64, error) {
+	var id uint64
+
+	query := `SELECT ISNULL(MAX(id), 0) FROM lot_changes`
+
+	if err := svc.tx.Get(&id, query, nil); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// DeleteLotChangesBefore deletes the changes before the time except the
+// last change of every lot, which keeps the state of the lot for the reads
+// of the other instances.
+func (svc *lotChangeService) DeleteLotChangesBefore(t time.Time) error {
+	arg := map[string]interface{}{
+		"created_at": t,
+	}
+
+	query := `
+		DELETE FROM lot_changes
+		WHERE created_at < :created_at
+		AND id NOT IN (SELECT MAX(id) FROM lot_changes GROUP BY lot_id)
+	`
+
+	if _, err := svc.tx.Exec(query, arg); err != nil {
+		return err
+	}
+
+	return nil
+}
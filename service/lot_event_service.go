@@ -0,0 +1,92 @@
+package service
+
+import (
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+)
+
+// lotEvents enables the event-sourced mode, every transition of the lot
+// writes the lot event.
+var lotEvents bool
+
+func EnableLotEvents(enable bool) {
+	lotEvents = enable
+}
+
+type lotEventService struct {
+	tx *db.Tx
+}
+
+func NewLotEventService(tx *db.Tx) *lotEventService {
+	return &lotEventService{tx}
+}
+
+func (svc *lotEventService) LotEvents(lotID uint) ([]*core.LotEvent, error) {
+	events := []*core.LotEvent{}
+
+	arg := map[string]interface{}{
+		"lot_id": lotID,
+	}
+
+	query := `
+		SELECT 
+			id,
+			type,
+			version,
+			payload,
+			created_at,
+			lot_id,
+			user_id
+		FROM lot_events 
+		WHERE lot_id = :lot_id
+		ORDER BY version
+	`
+
+	if err := svc.tx.Select(&events, query, arg); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// AppendLotEvent writes the event with the next version of the lot. It
+// does nothing if the event-sourced mode is disabled.
+func (svc *lotEventService) AppendLotEvent(eventType string, userID uint,
+	lot *core.Lot) error {
+	if !lotEvents {
+		return nil
+	}
+
+	event, err := core.NewLotEvent(eventType, userID, lot)
+	if err != nil {
+		return err
+	}
+
+	event.CreatedAt = svc.tx.Now()
+
+	query := `
+		INSERT INTO lot_events (
+			type,
+			version,
+			payload,
+			created_at,
+			lot_id,
+			user_id
+		)
+		SELECT 
+			:type,
+			ISNULL(MAX(version), 0) + 1,
+			:payload,
+			:created_at,
+			:lot_id,
+			:user_id
+		FROM lot_events WITH (UPDLOCK, HOLDLOCK)
+		WHERE lot_id = :lot_id
+	`
+
+	if _, err := svc.tx.Exec(query, event); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"database/sql"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+)
+
+type lotLeaseService struct {
+	tx *db.Tx
+}
+
+func NewLotLeaseService(tx *db.Tx) *lotLeaseService {
+	return &lotLeaseService{tx}
+}
+
+func (svc *lotLeaseService) LotLease(lotID uint) (*core.LotLease, error) {
+	lease := &core.LotLease{}
+
+	arg := map[string]interface{}{
+		"lot_id": lotID,
+	}
+
+	query := `
+		SELECT
+			lot_id,
+			instance,
+			address,
+			expires_at,
+			updated_at
+		FROM lot_leases
+		WHERE lot_id = :lot_id
+	`
+
+	if err := svc.tx.Get(lease, query, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, core.LotLeaseNotFound
+		}
+		return nil, err
+	}
+
+	return lease, nil
+}
+
+// AcquireLotLease takes the lease if it is free, expired or already held
+// by the instance of the lease. It returns the current lease of the lot,
+// which is held by another instance if the lease is not acquired.
+func (svc *lotLeaseService) AcquireLotLease(
+	lease *core.LotLease) (*core.LotLease, error) {
+	lease.UpdatedAt = svc.tx.Now()
+
+	query := `
+		UPDATE lot_leases WITH (UPDLOCK, HOLDLOCK) SET
+			instance = :instance,
+			address = :address,
+			expires_at = :expires_at,
+			updated_at = :updated_at
+		WHERE lot_id = :lot_id
+		AND (instance = :instance OR expires_at <= :updated_at)
+
+		IF @@ROWCOUNT = 0
+		INSERT INTO lot_leases (
+			lot_id,
+			instance,
+			address,
+			expires_at,
+			updated_at
+		)
+		SELECT
+			:lot_id,
+			:instance,
+			:address,
+			:expires_at,
+			:updated_at
+		WHERE NOT EXISTS (
+			SELECT 1 FROM lot_leases WITH (UPDLOCK, HOLDLOCK)
+			WHERE lot_id = :lot_id
+		)
+	`
+
+	if _, err := svc.tx.Exec(query, lease); err != nil {
+		return nil, err
+	}
+
+	return svc.LotLease(lease.LotID)
+}
+
+// RenewLotLease prolongs the lease held by the instance of the lease, if it
+// is not expired. It returns false if the lease is lost, the expired lease
+// may be already taken over by another instance.
+func (svc *lotLeaseService) RenewLotLease(lease *core.LotLease) (bool, error) {
+	lease.UpdatedAt = svc.tx.Now()
+
+	query := `
+		UPDATE lot_leases SET
+			address = :address,
+			expires_at = :expires_at,
+			updated_at = :updated_at
+		WHERE lot_id = :lot_id
+		AND instance = :instance
+		AND expires_at > :updated_at
+	`
+
+	result, err := svc.tx.Exec(query, lease)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// ReleaseLotLease deletes the lease if it is held by the instance of the
+// lease.
+func (svc *lotLeaseService) ReleaseLotLease(lease *core.LotLease) error {
+	query := `
+		DELETE FROM lot_leases
+		WHERE lot_id = :lot_id
+		AND instance = :instance
+	`
+
+	if _, err := svc.tx.Exec(query, lease); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (svc *lotLeaseService) DeleteLotLease(lotID uint) error {
+	arg := map[string]interface{}{
+		"lot_id": lotID,
+	}
+
+	query := `DELETE FROM lot_leases WHERE lot_id = :lot_id`
+
+	if _, err := svc.tx.Exec(query, arg); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnleasedLots returns the lots with the saved process state, which are not
+// held by any instance or whose lease is expired.
+func (svc *lotLeaseService) UnleasedLots() ([]uint, error) {
+	lotIDs := []uint{}
+
+	arg := map[string]interface{}{
+		"now": svc.tx.Now(),
+	}
+
+	query := `
+		SELECT p.lot_id
+		FROM processes p
+		LEFT JOIN lot_leases l ON l.lot_id = p.lot_id
+		WHERE l.lot_id IS NULL
+		OR l.expires_at <= :now
+	`
+
+	if err := svc.tx.Select(&lotIDs, query, arg); err != nil {
+		return nil, err
+	}
+
+	return lotIDs, nil
+}
+
+// RemoteLots returns the lots leased by the other instances.
+func (svc *lotLeaseService) RemoteLots(instance string) ([]uint, error) {
+	lotIDs := []uint{}
+
+	arg := map[string]interface{}{
+		"instance": instance,
+		"now":      svc.tx.Now(),
+	}
+
+	query := `
+		SELECT lot_id
+		FROM lot_leases
+		WHERE instance <> :instance
+		AND expires_at > :now
+	`
+
+	if err := svc.tx.Select(&lotIDs, query, arg); err != nil {
+		return nil, err
+	}
+
+	return lotIDs, nil
+}
+
+// DeleteExpiredLotLeases deletes the expired leases of the lots without the
+// process state, that is the leases of the completed lots.
+func (svc *lotLeaseService) DeleteExpiredLotLeases() error {
+	arg := map[string]interface{}{
+		"now": svc.tx.Now(),
+	}
+
+	query := `
+		DELETE FROM lot_leases
+		WHERE expires_at <= :now
+		AND NOT EXISTS (
+			SELECT 1 FROM processes p
+			WHERE p.lot_id = lot_leases.lot_id
+		)
+	`
+
+	if _, err := svc.tx.Exec(query, arg); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -21,7 +21,7 @@ func (svc *lotService) Lots(filter core.LotsFilter) ([]*core.Lot, error) {
 
 	arg := map[string]interface{}{}
 
-	var joinBets, whereGroups, whereState, orderLots string
+	var joinBets, whereGroups, wherePublished, whereState, orderLots string
 
 	if filter != nil {
 		whereGroups = `
@@ -36,9 +36,16 @@ func (svc *lotService) Lots(filter core.LotsFilter) ([]*core.Lot, error) {
 			arg["groups"] = filter.Group()
 		}
 
+		if filter.Executor().Level() == core.LevelUser {
+			wherePublished = `
+				AND (lots.publish_at IS NULL OR lots.publish_at <= :now)
+			`
+			arg["now"] = svc.tx.Now()
+		}
+
 		switch filter.State() {
 		case core.FilterStateActive:
-			if filter.Executor().Blocked {
+			if filter.Executor().IsBlocked(svc.tx.Now()) {
 				return lots, nil
 			}
 			whereState = `
@@ -95,14 +102,24 @@ func (svc *lotService) Lots(filter core.LotsFilter) ([]*core.Lot, error) {
 			lots.confirm,
 			lots.group_key,
 			lots.user_id,
-			lots.urgent
+			lots.urgent,
+			lots.time_zone,
+			lots.reserved_by,
+			lots.reserved_till,
+			lots.publish_at,
+			lots.expires_at,
+			lots.reserve_price,
+			lots.fallback,
+			groups.time_zone AS group_time_zone
 		FROM lots
+		LEFT JOIN groups ON groups.group_key = lots.group_key
 		%s
 		WHERE lots.deleted_at IS NULL
 		%s
 		%s
 		%s
-	`, joinBets, whereGroups, whereState, orderLots)
+		%s
+	`, joinBets, whereGroups, wherePublished, whereState, orderLots)
 
 	if err := svc.tx.Select(&lots, query, arg); err != nil {
 		return nil, err
@@ -125,7 +142,7 @@ func (svc *lotService) Lots(filter core.LotsFilter) ([]*core.Lot, error) {
 	}*/
 
 	query = `
-		SELECT id, value, winner, created_at, lot_id, user_id
+		SELECT id, value, winner, settled, auto, created_at, lot_id, user_id
 		FROM bets
 		WHERE lot_id IN (:ids) AND deleted_at IS NULL
 	`
@@ -240,12 +257,13 @@ func (svc *lotService) Lot(id uint, trashed bool) (*core.Lot, error) {
 
 	var where string
 	if !trashed {
-		where = "AND deleted_at IS NULL"
+		where = "AND lots.deleted_at IS NULL"
 	}
 
 	query := fmt.Sprintf(`
-		SELECT * FROM lots 
-		WHERE id = :id
+		SELECT lots.*, groups.time_zone AS group_time_zone FROM lots 
+		LEFT JOIN groups ON groups.group_key = lots.group_key
+		WHERE lots.id = :id
 		%s
 	`, where)
 
@@ -280,7 +298,7 @@ func (svc *lotService) Lot(id uint, trashed bool) (*core.Lot, error) {
 	}
 
 	query = `
-		SELECT id, value, winner, created_at, lot_id, user_id
+		SELECT id, value, winner, settled, auto, created_at, lot_id, user_id
 		FROM bets
 		WHERE lot_id = :id AND deleted_at IS NULL
 	`
@@ -325,8 +343,8 @@ func (svc *lotService) LotByObject(groupKey string, objectID uint) (*core.Lot, e
 }
 
 func (svc *lotService) CreateLot(lot *core.Lot) error {
-	lot.CreatedAt = now()
-	lot.UpdatedAt = now()
+	lot.CreatedAt = svc.tx.Now()
+	lot.UpdatedAt = svc.tx.Now()
 
 	query := `
 		INSERT INTO lots (
@@ -339,7 +357,12 @@ func (svc *lotService) CreateLot(lot *core.Lot) error {
 			object,
 			group_key,
 			user_id,
-			urgent
+			urgent,
+			time_zone,
+			reserve_price,
+			fallback,
+			publish_at,
+			expires_at
 		)
 		VALUES (
 			:rules, 
@@ -351,7 +374,12 @@ func (svc *lotService) CreateLot(lot *core.Lot) error {
 			:object,
 			:group_key,
 			:user_id,
-			:urgent
+			:urgent,
+			:time_zone,
+			:reserve_price,
+			:fallback,
+			:publish_at,
+			:expires_at
 		)
 	`
 
@@ -371,7 +399,7 @@ func (svc *lotService) CreateLot(lot *core.Lot) error {
 }
 
 func (svc *lotService) SaveLot(lot *core.Lot) error {
-	lot.UpdatedAt = now()
+	lot.UpdatedAt = svc.tx.Now()
 
 	query := `
 		UPDATE lots SET 
@@ -385,7 +413,14 @@ func (svc *lotService) SaveLot(lot *core.Lot) error {
 			object = :object,
 			confirm = :confirm,
 			complete = :complete,
-			urgent = :urgent
+			urgent = :urgent,
+			time_zone = :time_zone,
+			reserve_price = :reserve_price,
+			fallback = :fallback,
+			reserved_by = :reserved_by,
+			reserved_till = :reserved_till,
+			publish_at = :publish_at,
+			expires_at = :expires_at
 		WHERE id = :id`
 
 	if _, err := svc.tx.Exec(query, lot); err != nil {
@@ -396,7 +431,7 @@ func (svc *lotService) SaveLot(lot *core.Lot) error {
 }
 
 func (svc *lotService) DeleteLot(lot *core.Lot) error {
-	n := now()
+	n := svc.tx.Now()
 
 	lot.DeletedAt = &n
 
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"gitlab/nefco/auction/clock"
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/server/command"
 )
@@ -15,6 +16,7 @@ type Notify struct {
 	Event    command.Event
 	Receiver *core.User
 	lot      *core.Lot
+	clock    clock.Clock
 }
 
 func (n *Notify) Update(receiverID uint) {
@@ -25,7 +27,12 @@ func (n *Notify) Update(receiverID uint) {
 }
 
 func (n *Notify) Check(receiver *core.User) bool {
-	if receiver.Blocked {
+	now := n.clock.Now()
+	if receiver.IsBlocked(now) {
+		return false
+	}
+	if n.lot != nil && receiver.Level() == core.LevelUser &&
+		!n.lot.Published(now) {
 		return false
 	}
 	if n.lot == nil {
@@ -35,21 +42,23 @@ func (n *Notify) Check(receiver *core.User) bool {
 }
 
 type notifyService struct {
-	ch chan Notify
+	ch    chan Notify
+	clock clock.Clock
 }
 
-func NewNotifyService() *notifyService {
+func NewNotifyService(clk clock.Clock) *notifyService {
 	return &notifyService{
-		ch: make(chan Notify),
+		ch:    make(chan Notify),
+		clock: clk,
 	}
 }
 
 func (svc *notifyService) add(evt command.Event, lot *core.Lot) {
-	svc.ch <- Notify{Event: evt, lot: lot}
+	svc.ch <- Notify{Event: evt, lot: lot, clock: svc.clock}
 }
 
 func (svc *notifyService) addWithReceiver(evt command.Event, receiver *core.User) {
-	svc.ch <- Notify{Event: evt, Receiver: receiver}
+	svc.ch <- Notify{Event: evt, Receiver: receiver, clock: svc.clock}
 }
 
 func (svc *notifyService) Events() <-chan Notify {
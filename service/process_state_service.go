@@ -0,0 +1,127 @@
+package service
+
+import (
+	"database/sql"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+)
+
+type processStateService struct {
+	tx *db.Tx
+}
+
+func NewProcessStateService(tx *db.Tx) *processStateService {
+	return &processStateService{tx}
+}
+
+func (svc *processStateService) ProcessStates() ([]*core.ProcessState, error) {
+	states := []*core.ProcessState{}
+
+	query := `
+		SELECT 
+			lot_id,
+			[rule],
+			rule_index,
+			start_at,
+			end_at,
+			added,
+			state,
+			updated_at
+		FROM processes
+	`
+
+	if err := svc.tx.Select(&states, query, nil); err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (svc *processStateService) ProcessState(lotID uint) (*core.ProcessState, error) {
+	state := &core.ProcessState{}
+
+	arg := map[string]interface{}{
+		"lot_id": lotID,
+	}
+
+	query := `
+		SELECT 
+			lot_id,
+			[rule],
+			rule_index,
+			start_at,
+			end_at,
+			added,
+			state,
+			updated_at
+		FROM processes
+		WHERE lot_id = :lot_id
+	`
+
+	if err := svc.tx.Get(state, query, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, core.ProcessStateNotFound
+		}
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (svc *processStateService) SaveProcessState(state *core.ProcessState) error {
+	state.UpdatedAt = svc.tx.Now()
+
+	query := `
+		UPDATE processes SET 
+			[rule] = :rule,
+			rule_index = :rule_index,
+			start_at = :start_at,
+			end_at = :end_at,
+			added = :added,
+			state = :state,
+			updated_at = :updated_at
+		WHERE lot_id = :lot_id
+
+		IF @@ROWCOUNT = 0
+		INSERT INTO processes (
+			lot_id,
+			[rule],
+			rule_index,
+			start_at,
+			end_at,
+			added,
+			state,
+			updated_at
+		)
+		VALUES (
+			:lot_id,
+			:rule,
+			:rule_index,
+			:start_at,
+			:end_at,
+			:added,
+			:state,
+			:updated_at
+		)
+	`
+
+	if _, err := svc.tx.Exec(query, state); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (svc *processStateService) DeleteProcessState(lotID uint) error {
+	arg := map[string]interface{}{
+		"lot_id": lotID,
+	}
+
+	query := `DELETE FROM processes WHERE lot_id = :lot_id`
+
+	if _, err := svc.tx.Exec(query, arg); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"database/sql"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+	"gitlab/nefco/auction/errors"
+)
+
+var (
+	ProxyBetNotFound = errors.NotFound("Proxy bet not found")
+)
+
+type proxyBetService struct {
+	tx *db.Tx
+}
+
+func NewProxyBetService(tx *db.Tx) *proxyBetService {
+	return &proxyBetService{tx}
+}
+
+// ProxyBets returns the proxy bets of the lot, except for the proxy bets of
+// the users blocked now.
+func (svc *proxyBetService) ProxyBets(lotID uint) ([]*core.ProxyBet, error) {
+	proxyBets := []*core.ProxyBet{}
+
+	arg := map[string]interface{}{
+		"lot_id": lotID,
+		"now":    svc.tx.Now(),
+	}
+
+	query := `
+		SELECT 
+			p.id,
+			p.floor,
+			p.created_at,
+			p.deleted_at,
+			p.lot_id,
+			p.user_id
+		FROM proxy_bets p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.lot_id = :lot_id 
+		AND p.deleted_at IS NULL
+		AND NOT (u.blocked = 1 AND (u.blocked_until IS NULL OR u.blocked_until > :now))
+		ORDER BY p.created_at, p.id
+	`
+
+	if err := svc.tx.Select(&proxyBets, query, arg); err != nil {
+		return nil, err
+	}
+
+	return proxyBets, nil
+}
+
+func (svc *proxyBetService) UserProxyBet(lotID uint, userID uint) (*core.ProxyBet, error) {
+	proxyBet := &core.ProxyBet{}
+
+	arg := map[string]interface{}{
+		"lot_id":  lotID,
+		"user_id": userID,
+	}
+
+	query := `
+		SELECT 
+			id,
+			floor,
+			created_at,
+			deleted_at,
+			lot_id,
+			user_id
+		FROM proxy_bets 
+		WHERE lot_id = :lot_id 
+		AND user_id = :user_id
+		AND deleted_at IS NULL
+	`
+
+	if err := svc.tx.Get(proxyBet, query, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ProxyBetNotFound
+		}
+		return nil, err
+	}
+
+	return proxyBet, nil
+}
+
+func (svc *proxyBetService) CreateProxyBet(proxyBet *core.ProxyBet) error {
+	proxyBet.CreatedAt = svc.tx.Now()
+
+	query := `
+		INSERT INTO proxy_bets (
+			floor, 
+			created_at,
+			lot_id, 
+			user_id
+		)
+		VALUES (
+			:floor,
+			:created_at,
+			:lot_id, 
+			:user_id
+		)
+	`
+
+	result, err := svc.tx.Exec(query, proxyBet)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	proxyBet.ID = uint64(id)
+
+	return nil
+}
+
+func (svc *proxyBetService) DeleteProxyBet(proxyBet *core.ProxyBet) error {
+	n := svc.tx.Now()
+
+	proxyBet.DeletedAt = &n
+
+	query := `UPDATE proxy_bets SET deleted_at = :deleted_at WHERE id = :id`
+
+	if _, err := svc.tx.Exec(query, proxyBet); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"fmt"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SanctionBan      = "ban"
+	SanctionApproval = "approval"
+)
+
+const MaxScore = 100
+
+// ReliabilityPolicy is the sanction applied to the carrier whose score
+// falls below the score of the policy. The ban blocks bidding for the
+// duration, the approval blocks it until a manager approves the carrier by
+// PATCH /user/{userID}/unblock. The failures stay within the window, so
+// the next failure of the approved carrier blocks it again.
+type ReliabilityPolicy struct {
+	Score    uint          `mapstructure:"score"`
+	Action   string        `mapstructure:"action"`
+	Duration time.Duration `mapstructure:"duration"`
+}
+
+type ConfigReliability struct {
+	Window   time.Duration        `mapstructure:"window"`
+	Penalty  uint                 `mapstructure:"penalty"`
+	Policies []*ReliabilityPolicy `mapstructure:"policies"`
+}
+
+func DefaultConfigReliability() *ConfigReliability {
+	return &ConfigReliability{
+		Window:   90 * 24 * time.Hour,
+		Penalty:  10,
+		Policies: []*ReliabilityPolicy{},
+	}
+}
+
+var cfgReliability = DefaultConfigReliability()
+
+func SetConfigReliability(cfg *ConfigReliability) {
+	cfgReliability = cfg
+}
+
+type reliabilityService struct {
+	tx     *db.Tx
+	cfg    *ConfigReliability
+	logger *zap.Logger
+}
+
+func NewReliabilityService(tx *db.Tx) *reliabilityService {
+	return &reliabilityService{
+		tx:     tx,
+		cfg:    cfgReliability,
+		logger: zap.L().Named("reliability_service"),
+	}
+}
+
+// failures counts the failures of the carriers within the window: the lots
+// not confirmed in time, the bets canceled after booking and the deleted
+// confirmations.
+const failuresQuery = `
+	SELECT carrier_id, COUNT(*) AS failures FROM (
+		SELECT current_price_user_id AS carrier_id FROM history
+		WHERE action IN ('lot_no_confirm', 'lot_delete_confirmation')
+		AND created_at >= :since
+		UNION ALL
+		SELECT user_id AS carrier_id FROM history
+		WHERE action = 'bet_canceled'
+		AND [rule] = 'confirm'
+		AND created_at >= :since
+	) AS f
+	WHERE carrier_id IS NOT NULL
+	%s
+	GROUP BY carrier_id
+`
+
+type carrierFailures struct {
+	CarrierID uint `db:"carrier_id"`
+	Failures  uint `db:"failures"`
+}
+
+// Scores returns the scores of the carriers with failures, the score of
+// other carriers is the max score.
+func (svc *reliabilityService) Scores() (map[uint]uint, error) {
+	return svc.scores("", map[string]interface{}{
+		"since": svc.tx.Now().Add(-svc.cfg.Window),
+	})
+}
+
+func (svc *reliabilityService) Score(userID uint) (uint, error) {
+	scores, err := svc.scores("AND carrier_id = :user_id", map[string]interface{}{
+		"since":   svc.tx.Now().Add(-svc.cfg.Window),
+		"user_id": userID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if score, ok := scores[userID]; ok {
+		return score, nil
+	}
+	return MaxScore, nil
+}
+
+func (svc *reliabilityService) scores(where string,
+	arg map[string]interface{}) (map[uint]uint, error) {
+	rows := []*carrierFailures{}
+
+	query := fmt.Sprintf(failuresQuery, where)
+
+	if err := svc.tx.Select(&rows, query, arg); err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint]uint, len(rows))
+
+	for _, row := range rows {
+		penalty := row.Failures * svc.cfg.Penalty
+		if penalty > MaxScore {
+			penalty = MaxScore
+		}
+		scores[row.CarrierID] = MaxScore - penalty
+	}
+
+	return scores, nil
+}
+
+// Check applies the strictest policy matching the score of the carrier.
+// Managers and root are never sanctioned.
+func (svc *reliabilityService) Check(userID uint) error {
+	logger := svc.logger.Named("check").With(zap.Uint("user_id", userID))
+
+	if len(svc.cfg.Policies) == 0 {
+		return nil
+	}
+
+	exempt, err := svc.exempt(userID)
+	if err != nil {
+		logger.Error("get user failed", zap.Error(err))
+		return err
+	}
+	if exempt {
+		return nil
+	}
+
+	score, err := svc.Score(userID)
+	if err != nil {
+		logger.Error("get score failed", zap.Error(err))
+		return err
+	}
+
+	policies := make([]*ReliabilityPolicy, len(svc.cfg.Policies))
+	copy(policies, svc.cfg.Policies)
+
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Score < policies[j].Score
+	})
+
+	for _, policy := range policies {
+		if score >= policy.Score {
+			continue
+		}
+
+		logger.Info("sanction",
+			zap.Uint("score", score),
+			zap.String("action", policy.Action),
+		)
+
+		switch policy.Action {
+		case SanctionBan:
+			return svc.ban(userID, svc.tx.Now().Add(policy.Duration))
+		case SanctionApproval:
+			return changeUserBlocked(userID, true, nil, svc.tx)
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// exempt reports whether the user is root or a manager.
+func (svc *reliabilityService) exempt(userID uint) (bool, error) {
+	if userID == core.RootUserID {
+		return true, nil
+	}
+
+	user := &core.User{}
+
+	arg := map[string]interface{}{
+		"id": userID,
+	}
+
+	query := `SELECT back_key FROM users WHERE id = :id`
+
+	if err := svc.tx.Get(user, query, arg); err != nil {
+		return false, err
+	}
+
+	return user.Level() == core.LevelManager, nil
+}
+
+// ban blocks the carrier until the time, the longer block is kept.
+func (svc *reliabilityService) ban(userID uint, until time.Time) error {
+	user := &core.User{}
+
+	arg := map[string]interface{}{
+		"id": userID,
+	}
+
+	query := `SELECT blocked, blocked_until FROM users WHERE id = :id`
+
+	if err := svc.tx.Get(user, query, arg); err != nil {
+		return err
+	}
+
+	if user.Blocked && (user.BlockedUntil == nil || user.BlockedUntil.After(until)) {
+		return nil
+	}
+
+	return changeUserBlocked(userID, true, &until, svc.tx)
+}
@@ -1,7 +0,0 @@
-package service
-
-import "time"
-
-func now() time.Time {
-	return time.Now().UTC()
-}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"database/sql"
+	"gitlab/nefco/auction/core"
+	"gitlab/nefco/auction/db"
+)
+
+type templateService struct {
+	tx *db.Tx
+}
+
+func NewTemplateService(tx *db.Tx) *templateService {
+	return &templateService{tx}
+}
+
+func (svc *templateService) Templates() ([]*core.LotTemplate, error) {
+	templates := []*core.LotTemplate{}
+
+	query := `
+		SELECT * FROM lot_templates
+		WHERE deleted_at IS NULL
+	`
+
+	if err := svc.tx.Select(&templates, query, nil); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (svc *templateService) Template(id uint) (*core.LotTemplate, error) {
+	template := &core.LotTemplate{}
+
+	arg := map[string]interface{}{
+		"id": id,
+	}
+
+	query := `
+		SELECT * FROM lot_templates
+		WHERE id = :id
+		AND deleted_at IS NULL
+	`
+
+	if err := svc.tx.Get(template, query, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, core.TemplateNotFound
+		}
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (svc *templateService) CreateTemplate(template *core.LotTemplate) error {
+	template.CreatedAt = svc.tx.Now()
+	template.UpdatedAt = svc.tx.Now()
+
+	query := `
+		INSERT INTO lot_templates (
+			name,
+			lot,
+			recurrence,
+			start_at,
+			next_object_id,
+			last_run_at,
+			created_at,
+			updated_at,
+			user_id
+		)
+		VALUES (
+			:name,
+			:lot,
+			:recurrence,
+			:start_at,
+			:next_object_id,
+			:last_run_at,
+			:created_at,
+			:updated_at,
+			:user_id
+		)
+	`
+
+	result, err := svc.tx.Exec(query, template)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	template.ID = uint(id)
+
+	return nil
+}
+
+func (svc *templateService) SaveTemplate(template *core.LotTemplate) error {
+	template.UpdatedAt = svc.tx.Now()
+
+	query := `
+		UPDATE lot_templates SET
+			name = :name,
+			lot = :lot,
+			recurrence = :recurrence,
+			start_at = :start_at,
+			next_object_id = :next_object_id,
+			last_run_at = :last_run_at,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+
+	if _, err := svc.tx.Exec(query, template); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (svc *templateService) DeleteTemplate(template *core.LotTemplate) error {
+	n := svc.tx.Now()
+
+	template.DeletedAt = &n
+
+	query := `UPDATE lot_templates SET deleted_at = :deleted_at WHERE id = :id`
+
+	if _, err := svc.tx.Exec(query, template); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"gitlab/nefco/auction/core"
 	"gitlab/nefco/auction/db"
 	"gitlab/nefco/auction/errors"
+	"time"
 )
 
 type userService struct {
@@ -20,7 +21,7 @@ func (svc *userService) Users() ([]*core.User, error) {
 	users := []*core.User{}
 
 	query := `
-		SELECT id, username, object_type, back_key
+		SELECT id, username, blocked, blocked_until, object_type, back_key
 		FROM users 
 	`
 
@@ -60,6 +61,7 @@ func (svc *userService) User(id uint) (*core.User, error) {
 			id,
 			username,
 			blocked,
+			blocked_until,
 			object_type,
 			back_key,
 			employer_name,
@@ -241,7 +243,7 @@ func (svc *userService) BlockUser(userID uint) error {
 		return err
 	}
 
-	return changeUserBlocked(userID, true, svc.tx)
+	return changeUserBlocked(userID, true, nil, svc.tx)
 
 }
 
@@ -251,7 +253,7 @@ func (svc *userService) UnblockUser(userID uint) error {
 		return err
 	}
 
-	return changeUserBlocked(userID, false, svc.tx)
+	return changeUserBlocked(userID, false, nil, svc.tx)
 }
 
 func validateBlockParams(userID uint, tx *db.Tx) error {
@@ -278,12 +280,17 @@ func validateBlockParams(userID uint, tx *db.Tx) error {
 	return nil
 }
 
-func changeUserBlocked(userID uint, block bool, tx *db.Tx) error {
-	query := `UPDATE users SET blocked = :blocked WHERE id = :id`
+func changeUserBlocked(userID uint, block bool, until *time.Time,
+	tx *db.Tx) error {
+	query := `
+		UPDATE users SET blocked = :blocked, blocked_until = :blocked_until
+		WHERE id = :id
+	`
 
 	arg := map[string]interface{}{
 		"id":       userID,
 		"blocked": block,
+		"blocked_until": until,
 	}
 
 	_ , err := tx.Exec(query, arg)